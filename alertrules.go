@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// priceHistoryWindow is the period the recent price history variables, such as lowest_90d, cover
+const priceHistoryWindow = 90 * 24 * time.Hour
+
+// AlertRule is a custom rule written as an expression, notifying whenever a listing starts to match it
+type AlertRule struct {
+	Name       string
+	Expression string
+	program    *vm.Program
+}
+
+// RuleEnv holds the variables available to alert rule expressions for a scraped listing
+type RuleEnv struct {
+	Product        string   `expr:"product"`
+	Category       string   `expr:"category"`
+	Retailer       string   `expr:"retailer"`
+	Label          string   `expr:"label"`
+	Price          float64  `expr:"price"`
+	BasePrice      float64  `expr:"base_price"`
+	CachedPrice    float64  `expr:"cached_price"`
+	HasCachedPrice bool     `expr:"has_cached_price"`
+	UnitPrice      float64  `expr:"unit_price"`
+	DropPct        float64  `expr:"drop_pct"`
+	DiscountPct    float64  `expr:"discount_pct"`
+	Lowest90d      float64  `expr:"lowest_90d"`
+	Highest90d     float64  `expr:"highest_90d"`
+	Average90d     float64  `expr:"average_90d"`
+	LowestEver     float64  `expr:"lowest_ever"`
+	InStock        bool     `expr:"in_stock"`
+	MemberPrice    bool     `expr:"member_price"`
+	Promotions     []string `expr:"promotions"`
+}
+
+type PriceStats struct {
+	Lowest90d, Highest90d, Average90d, LowestEver float64
+}
+
+type RuleMatch struct {
+	Rule    *AlertRule
+	Product *Product
+	Scrape  SuccessScrape
+}
+
+func GetAlertRules(config Config) ([]AlertRule, error) {
+	var rules []AlertRule
+	names := make(map[string]bool)
+
+	for i, r := range config.Rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i+1)
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("more than one rule is named %q", r.Name)
+		}
+		names[r.Name] = true
+
+		program, err := expr.Compile(r.When, expr.Env(RuleEnv{}), expr.AsBool())
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression for rule %q: %v", r.Name, err)
+		}
+
+		rules = append(rules, AlertRule{Name: r.Name, Expression: r.When, program: program})
+	}
+
+	return rules, nil
+}
+
+func newRuleEnv(product *Product, scrape SuccessScrape, stats PriceStats) RuleEnv {
+	env := RuleEnv{
+		Product:     product.Name,
+		Category:    product.Category,
+		Retailer:    scrape.Retailer.Name,
+		Label:       scrape.Label,
+		Price:       scrape.Price,
+		BasePrice:   product.BasePrice,
+		UnitPrice:   scrape.Price,
+		Lowest90d:   stats.Lowest90d,
+		Highest90d:  stats.Highest90d,
+		Average90d:  stats.Average90d,
+		LowestEver:  stats.LowestEver,
+		InStock:     !scrape.OutOfStock,
+		MemberPrice: scrape.PriceType == PriceTypeMember,
+		Promotions:  scrape.Promotions,
+	}
+
+	if scrape.Size > 0 {
+		quantity, _ := unitPriceQuantity(scrape.Unit)
+		env.UnitPrice = scrape.Price / scrape.Size * quantity
+	}
+
+	if product.BasePrice > 0 {
+		env.DiscountPct = (product.BasePrice - scrape.ComparablePrice(product)) / product.BasePrice * 100
+	}
+
+	if scrape.CachedPrice != nil {
+		env.CachedPrice = *scrape.CachedPrice
+		env.HasCachedPrice = true
+		if env.CachedPrice > 0 {
+			env.DropPct = (env.CachedPrice - scrape.Price) / env.CachedPrice * 100
+		}
+	}
+
+	return env
+}
+
+// EvaluateAlertRules checks each scraped listing against the rules, returning the listings that match a rule they
+// didn't match on the previous scrape, and whether each listing matched each rule to store once they're notified
+func EvaluateAlertRules(cache *Cache, rules []AlertRule, prices map[*Product][]SuccessScrape) ([]RuleMatch, map[string]map[CacheKey]bool, error) {
+	if len(rules) == 0 {
+		return nil, nil, nil
+	}
+
+	stats, err := cache.GetPriceStats(time.Now().Add(-priceHistoryWindow))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting price history: %v", err)
+	}
+
+	previous, err := cache.GetRuleMatches()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting rule matches: %v", err)
+	}
+
+	var found []RuleMatch
+	results := make(map[string]map[CacheKey]bool)
+	for i := range rules {
+		rule := &rules[i]
+		results[rule.Name] = make(map[CacheKey]bool)
+
+		for product, scrapes := range prices {
+			for _, scrape := range scrapes {
+				key := scrape.Key(product)
+
+				output, err := expr.Run(rule.program, newRuleEnv(product, scrape, stats[key]))
+				if err != nil {
+					return nil, nil, fmt.Errorf("error evaluating rule %q for %s at %s: %v", rule.Name, product.Name, listingName(scrape.Retailer, scrape.Label), err)
+				}
+
+				matched := output.(bool)
+				results[rule.Name][key] = matched
+				if matched && !previous[rule.Name][key] {
+					found = append(found, RuleMatch{Rule: rule, Product: product, Scrape: scrape})
+				}
+			}
+		}
+	}
+
+	return found, results, nil
+}
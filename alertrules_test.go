@@ -0,0 +1,149 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetAlertRules(t *testing.T) {
+	config := Config{Rules: []AlertRuleTOML{
+		{Name: "Cheap at Boots", When: `price < 8 && retailer == "Boots" && in_stock`},
+		{Name: "New low", When: `drop_pct >= 20 || price <= lowest_90d`},
+	}}
+	rules, err := GetAlertRules(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules) != 2 {
+		t.Errorf("unexpected length: expected 2, got %d", len(rules))
+	}
+
+	invalid := []struct {
+		rule     AlertRuleTOML
+		expected string
+	}{
+		// Unknown variable
+		{AlertRuleTOML{Name: "Typo", When: `prise < 8`}, "unknown name prise"},
+		// Not a condition
+		{AlertRuleTOML{Name: "Not bool", When: `price * 2`}, "expected bool"},
+		{AlertRuleTOML{Name: "", When: `price < 8`}, "has no name"},
+	}
+	for _, test := range invalid {
+		_, err = GetAlertRules(Config{Rules: []AlertRuleTOML{test.rule}})
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%q: expected an error containing %q, got %v", test.rule.When, test.expected, err)
+		}
+	}
+}
+
+func TestEvaluateAlertRules(t *testing.T) {
+	cache := newTestCache(t)
+	product := &Product{Name: "Test Product", BasePrice: 10.00}
+	retailer := &Retailer{Name: "Boots"}
+
+	rules, err := GetAlertRules(Config{Rules: []AlertRuleTOML{{Name: "New low", When: `has_cached_price && price < lowest_90d`}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	runs := []struct {
+		price    float64
+		expected int
+	}{
+		// No history yet => no match
+		{9.00, 0},
+		// Below the lowest price so far => match
+		{8.00, 1},
+		// Still matching => not notified again
+		{7.00, 0},
+		// No longer below the lowest price => no match
+		{7.50, 0},
+		// Matches again => notified again
+		{6.00, 1},
+	}
+
+	var cachedPrice *float64
+	for i, run := range runs {
+		prices := map[*Product][]SuccessScrape{product: {{Retailer: retailer, Price: run.price, Url: "https://test.com/1", CachedPrice: cachedPrice}}}
+
+		matches, results, err := EvaluateAlertRules(cache, rules, prices)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(matches) != run.expected {
+			t.Errorf("run %d: unexpected matches: expected %d, got %d", i+1, run.expected, len(matches))
+		}
+
+		err = cache.SetRuleMatches(results)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		err = cache.SetScrapes(prices)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		cachedPrice = floatPtr(run.price)
+	}
+}
+
+func TestEvaluateAlertRulesUnsaved(t *testing.T) {
+	cache := newTestCache(t)
+	product := &Product{Name: "Test Product", BasePrice: 10.00}
+	prices := map[*Product][]SuccessScrape{product: {{Retailer: &Retailer{Name: "Boots"}, Price: 8.00, Url: "https://test.com/1"}}}
+
+	rules, err := GetAlertRules(Config{Rules: []AlertRuleTOML{{Name: "Cheap", When: `price < 9`}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Matches that weren't stored, e.g. as notifying them failed, are found again
+	for run := 1; run <= 2; run++ {
+		matches, _, err := EvaluateAlertRules(cache, rules, prices)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(matches) != 1 {
+			t.Errorf("run %d: unexpected matches: expected 1, got %d", run, len(matches))
+		}
+	}
+}
+
+func TestPriceHistoryPruned(t *testing.T) {
+	cache := newTestCache(t)
+	product := &Product{Name: "Test Product"}
+	retailer := &Retailer{Name: "Boots"}
+
+	old := time.Now().Add(-2 * priceHistoryWindow).Unix()
+	for _, price := range []int{500, 400, 450, 400} {
+		_, err := cache.db.Exec("INSERT INTO price_history (provider, product, label, price, scraped_at) VALUES (?, ?, ?, ?, ?)", retailer.Name, product.Name, "", price, old)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	err := cache.SetScrapes(map[*Product][]SuccessScrape{product: {{Retailer: retailer, Price: 6.00, Url: "https://test.com/1"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var count int
+	err = cache.db.QueryRow("SELECT COUNT(*) FROM price_history").Scan(&count)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The lowest old price and this scrape's
+	if count != 2 {
+		t.Errorf("unexpected history rows: expected 2, got %d", count)
+	}
+
+	stats, err := cache.GetPriceStats(time.Now().Add(-priceHistoryWindow))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := PriceStats{Lowest90d: 6.00, Highest90d: 6.00, Average90d: 6.00, LowestEver: 4.00}
+	if actual := stats[CacheKey{Retailer: retailer.Name, Product: product.Name}]; actual != expected {
+		t.Errorf("unexpected stats: expected %+v, got %+v", expected, actual)
+	}
+}
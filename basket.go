@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+)
+
+type Basket struct {
+	Name     string
+	Products []*Product
+	Notify   bool
+}
+
+type BasketLine struct {
+	Product  *Product
+	Retailer *Retailer
+	Label    string
+	Url      string
+	Price    float64
+	// The price scaled to the product's own pack size, which the basket total is made up of
+	ComparablePrice float64
+}
+
+type BasketOrder struct {
+	Retailer *Retailer
+	Lines    []BasketLine
+	Subtotal float64
+	Delivery float64
+	// Whether the order is collected in store, in which case Delivery is the collection fee
+	Collect bool
+}
+
+type BasketPlan struct {
+	Basket *Basket
+	Orders []BasketOrder
+	// Products without a cached price at any retailer, which are left out of the totals
+	Unavailable []*Product
+	Total       float64
+	BaseTotal   float64
+}
+
+func (b BasketPlan) Saving() float64 {
+	return b.BaseTotal - b.Total
+}
+
+// Available reports whether any product in the basket has a price, so the plan has a total
+func (b BasketPlan) Available() bool {
+	return len(b.Orders) > 0
+}
+
+func GetBaskets(config Config, products Products) ([]Basket, error) {
+	byName := make(map[string]*Product)
+	for i := range products {
+		byName[products[i].Name] = &products[i]
+	}
+
+	var baskets []Basket
+	for _, b := range config.Baskets {
+		basket := Basket{Name: b.Name, Notify: b.Notify}
+
+		for _, name := range b.Products {
+			product, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown product %s in basket %s", name, b.Name)
+			}
+			basket.Products = append(basket.Products, product)
+		}
+
+		baskets = append(baskets, basket)
+	}
+
+	return baskets, nil
+}
+
+func FindBasket(baskets []Basket, name string) (*Basket, error) {
+	for i := range baskets {
+		if baskets[i].Name == name {
+			return &baskets[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown basket %s", name)
+}
+
+// Optimise finds the cheapest way to split the basket across retailers using the cached prices, taking each
+// retailer's delivery charge, free delivery threshold and click and collect into account. Every choice of listing for
+// each product is tried, so an item can be bought somewhere other than its cheapest listing to reach free delivery,
+// with listings compared for the product's own pack size.
+func (b *Basket) Optimise(cachedPrices map[CacheKey]float64) BasketPlan {
+	plan := BasketPlan{Basket: b}
+
+	var options [][]BasketLine
+	for _, product := range b.Products {
+		var productOptions []BasketLine
+		for _, link := range product.Links {
+			key := CacheKey{Retailer: link.Retailer.Name, Product: product.Name, Label: link.Label}
+			if price, ok := cachedPrices[key]; ok {
+				productOptions = append(productOptions, BasketLine{
+					Product:         product,
+					Retailer:        link.Retailer,
+					Label:           link.Label,
+					Url:             link.Url,
+					Price:           price,
+					ComparablePrice: comparablePrice(product, link.Size, price),
+				})
+			}
+		}
+
+		if len(productOptions) == 0 {
+			plan.Unavailable = append(plan.Unavailable, product)
+			continue
+		}
+
+		options = append(options, productOptions)
+		plan.BaseTotal += product.BasePrice
+	}
+
+	if len(options) == 0 {
+		return plan
+	}
+
+	// The cheapest remaining items give a lower bound on the cost of the rest of the basket, as delivery is never negative
+	minRemaining := make([]float64, len(options)+1)
+	for i := len(options) - 1; i >= 0; i-- {
+		cheapest := math.Inf(1)
+		for _, option := range options[i] {
+			cheapest = min(cheapest, option.ComparablePrice)
+		}
+		minRemaining[i] = minRemaining[i+1] + cheapest
+	}
+
+	best := math.Inf(1)
+	var bestLines []BasketLine
+	lines := make([]BasketLine, len(options))
+
+	var search func(i int, itemsCost float64)
+	search = func(i int, itemsCost float64) {
+		if itemsCost+minRemaining[i] >= best {
+			return
+		}
+
+		if i == len(options) {
+			if total := basketTotal(lines); total < best {
+				best = total
+				bestLines = append([]BasketLine(nil), lines...)
+			}
+			return
+		}
+
+		for _, option := range options[i] {
+			lines[i] = option
+			search(i+1, itemsCost+option.ComparablePrice)
+		}
+	}
+	search(0, 0)
+
+	orders := make(map[*Retailer]*BasketOrder)
+	for _, line := range bestLines {
+		order, ok := orders[line.Retailer]
+		if !ok {
+			order = &BasketOrder{Retailer: line.Retailer}
+			orders[line.Retailer] = order
+		}
+
+		order.Lines = append(order.Lines, line)
+		order.Subtotal += line.Price
+		plan.Total += line.ComparablePrice
+	}
+
+	for _, order := range orders {
+		order.Delivery, order.Collect = order.Retailer.FulfilmentCost(order.Subtotal)
+		plan.Orders = append(plan.Orders, *order)
+		plan.Total += order.Delivery
+	}
+
+	sort.Slice(plan.Orders, func(i, j int) bool {
+		return plan.Orders[i].Retailer.Name < plan.Orders[j].Retailer.Name
+	})
+
+	return plan
+}
+
+// basketTotal is the cost of buying the lines for each product's pack size, plus delivery for each retailer's order
+func basketTotal(lines []BasketLine) float64 {
+	var total float64
+	subtotals := make(map[*Retailer]float64)
+	for _, line := range lines {
+		total += line.ComparablePrice
+		subtotals[line.Retailer] += line.Price
+	}
+
+	for retailer, subtotal := range subtotals {
+		cost, _ := retailer.FulfilmentCost(subtotal)
+		total += cost
+	}
+
+	return total
+}
+
+// NotifyBaskets sends the plan for each basket with notifications enabled, whenever its total has changed since
+// it was last sent
+func NotifyBaskets(ctx context.Context, baskets []Basket, cache *Cache, client Client) error {
+	cachedPrices, err := cache.GetScrapes()
+	if err != nil {
+		return fmt.Errorf("error getting cached prices: %v", err)
+	}
+
+	lastTotals, err := cache.GetBasketTotals()
+	if err != nil {
+		return fmt.Errorf("error getting basket totals: %v", err)
+	}
+
+	for i := range baskets {
+		if !baskets[i].Notify {
+			continue
+		}
+
+		plan := baskets[i].Optimise(cachedPrices)
+		if !plan.Available() {
+			continue
+		}
+		if lastTotal, ok := lastTotals[plan.Basket.Name]; ok && math.Abs(lastTotal-plan.Total) < 0.01 {
+			continue
+		}
+
+		err = notifyBasket(ctx, plan, client)
+		if err != nil {
+			return fmt.Errorf("error notifying basket %s: %v", plan.Basket.Name, err)
+		}
+
+		err = cache.SetBasketTotal(plan.Basket.Name, plan.Total)
+		if err != nil {
+			return fmt.Errorf("error storing basket total: %v", err)
+		}
+	}
+
+	return nil
+}
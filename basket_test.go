@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestOptimiseBasket(t *testing.T) {
+	boots := &Retailer{Name: "Boots", DeliveryCharge: 4.50, FreeDeliveryThreshold: 25.00}
+	superdrug := &Retailer{Name: "Superdrug", DeliveryCharge: 3.99, FreeDeliveryThreshold: 15.00}
+
+	cleanser := Product{Name: "Cleanser", BasePrice: 12.00, Links: []*Link{{Retailer: boots}, {Retailer: superdrug}}}
+	serum := Product{Name: "Serum", BasePrice: 15.00, Links: []*Link{{Retailer: boots}, {Retailer: superdrug}}}
+	spf := Product{Name: "SPF", BasePrice: 10.00, Links: []*Link{{Retailer: boots}}}
+	basket := Basket{Name: "Routine", Products: []*Product{&cleanser, &serum, &spf}}
+
+	cachedPrices := map[CacheKey]float64{
+		{Retailer: "Boots", Product: "Cleanser"}:     10.00,
+		{Retailer: "Superdrug", Product: "Cleanser"}: 9.00,
+		{Retailer: "Boots", Product: "Serum"}:        13.00,
+		{Retailer: "Superdrug", Product: "Serum"}:    12.00,
+		{Retailer: "Boots", Product: "SPF"}:          8.00,
+	}
+
+	// Buying everything at Boots reaches free delivery, which beats splitting the basket and paying for delivery
+	plan := basket.Optimise(cachedPrices)
+	if len(plan.Orders) != 1 || plan.Orders[0].Retailer != boots {
+		t.Fatalf("expected a single Boots order, got %+v", plan.Orders)
+	}
+	if math.Abs(plan.Total-31.00) > 0.001 {
+		t.Errorf("unexpected total: expected 31.00, got %.2f", plan.Total)
+	}
+	if math.Abs(plan.Saving()-6.00) > 0.001 {
+		t.Errorf("unexpected saving: expected 6.00, got %.2f", plan.Saving())
+	}
+
+	// Without a price for the SPF, splitting gets free delivery at Superdrug
+	delete(cachedPrices, CacheKey{Retailer: "Boots", Product: "SPF"})
+	plan = basket.Optimise(cachedPrices)
+	if len(plan.Unavailable) != 1 || plan.Unavailable[0] != &spf {
+		t.Errorf("expected the SPF to be unavailable, got %+v", plan.Unavailable)
+	}
+	if len(plan.Orders) != 1 || plan.Orders[0].Retailer != superdrug {
+		t.Fatalf("expected a single Superdrug order, got %+v", plan.Orders)
+	}
+	if math.Abs(plan.Total-21.00) > 0.001 {
+		t.Errorf("unexpected total: expected 21.00, got %.2f", plan.Total)
+	}
+}
+
+func TestOptimiseBasketPackSizes(t *testing.T) {
+	boots := &Retailer{Name: "Boots"}
+	superdrug := &Retailer{Name: "Superdrug"}
+
+	serum := Product{Name: "Serum", BasePrice: 10.00, Size: 30, Unit: "ml", Links: []*Link{
+		{Retailer: boots, Size: 30, Unit: "ml"},
+		{Retailer: superdrug, Label: "Twin pack", Size: 60, Unit: "ml"},
+	}}
+	basket := Basket{Name: "Routine", Products: []*Product{&serum}}
+
+	cachedPrices := map[CacheKey]float64{
+		{Retailer: "Boots", Product: "Serum"}:                         8.00,
+		{Retailer: "Superdrug", Product: "Serum", Label: "Twin pack"}: 14.00,
+	}
+
+	// The twin pack costs more but is cheaper for the product's own size
+	plan := basket.Optimise(cachedPrices)
+	if len(plan.Orders) != 1 || plan.Orders[0].Retailer != superdrug {
+		t.Fatalf("expected a single Superdrug order, got %+v", plan.Orders)
+	}
+	if math.Abs(plan.Orders[0].Subtotal-14.00) > 0.001 {
+		t.Errorf("unexpected subtotal: expected 14.00, got %.2f", plan.Orders[0].Subtotal)
+	}
+	if math.Abs(plan.Total-7.00) > 0.001 {
+		t.Errorf("unexpected total: expected 7.00, got %.2f", plan.Total)
+	}
+
+	client := &TestClient{}
+	err := notifyBasket(context.Background(), plan, client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(client.message, "£14.00 (£7.00 per 30ml)") {
+		t.Errorf("expected the pack-size price to be shown, got: %s", client.message)
+	}
+}
+
+func TestOptimiseBasketUnavailable(t *testing.T) {
+	serum := Product{Name: "Serum", BasePrice: 10.00, Links: []*Link{{Retailer: &Retailer{Name: "Boots"}}}}
+	basket := Basket{Name: "Routine", Products: []*Product{&serum}}
+
+	plan := basket.Optimise(map[CacheKey]float64{})
+	if plan.Available() {
+		t.Fatalf("expected the basket to be unavailable, got %+v", plan.Orders)
+	}
+
+	client := &TestClient{}
+	err := notifyBasket(context.Background(), plan, client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(client.message, "Unavailable: none of the products have a price yet") || strings.Contains(client.message, "Total") {
+		t.Errorf("expected the basket to be reported as unavailable, got: %s", client.message)
+	}
+}
+
+func TestOptimiseBasketFreeDelivery(t *testing.T) {
+	a := &Retailer{Name: "A", DeliveryCharge: 5.00, FreeDeliveryThreshold: 20.00}
+	b := &Retailer{Name: "B"}
+
+	x := Product{Name: "X", BasePrice: 15.00, Links: []*Link{{Retailer: a}, {Retailer: b}}}
+	y := Product{Name: "Y", BasePrice: 15.00, Links: []*Link{{Retailer: a}, {Retailer: b}}}
+	z := Product{Name: "Z", BasePrice: 15.00, Links: []*Link{{Retailer: a}, {Retailer: b}}}
+	basket := Basket{Name: "Routine", Products: []*Product{&x, &y, &z}}
+
+	cachedPrices := map[CacheKey]float64{
+		{Retailer: "A", Product: "X"}: 10.00,
+		{Retailer: "B", Product: "X"}: 9.00,
+		{Retailer: "A", Product: "Y"}: 10.00,
+		{Retailer: "B", Product: "Y"}: 50.00,
+		{Retailer: "A", Product: "Z"}: 30.00,
+		{Retailer: "B", Product: "Z"}: 10.00,
+	}
+
+	// X is cheaper at B, but buying it at A alongside Y reaches A's free delivery
+	plan := basket.Optimise(cachedPrices)
+	if math.Abs(plan.Total-30.00) > 0.001 {
+		t.Errorf("unexpected total: expected 30.00, got %.2f", plan.Total)
+	}
+	if len(plan.Orders) != 2 || len(plan.Orders[0].Lines) != 2 || plan.Orders[0].Delivery != 0 || plan.Orders[1].Lines[0].Product != &z {
+		t.Errorf("expected X and Y at A with free delivery and Z at B, got %+v", plan.Orders)
+	}
+}
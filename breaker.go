@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+type Breaker struct {
+	State    BreakerState
+	OpenedAt time.Time
+}
+
+type BreakerDecision int
+
+const (
+	BreakerAllow BreakerDecision = iota
+	BreakerProbe
+	BreakerSkip
+)
+
+// Breakers holds a circuit breaker for each retailer, so a retailer that is blocking us or down stops being
+// scraped until its cool-down has passed
+type Breakers struct {
+	config   CircuitBreaker
+	logger   *slog.Logger
+	breakers map[string]*Breaker
+}
+
+func LoadBreakers(cache *Cache, config CircuitBreaker, logger *slog.Logger) (*Breakers, error) {
+	breakers, err := cache.GetBreakers()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Breakers{config: config, logger: logger, breakers: breakers}, nil
+}
+
+func (b *Breakers) get(retailer *Retailer) *Breaker {
+	breaker, ok := b.breakers[retailer.Name]
+	if !ok {
+		breaker = &Breaker{State: BreakerClosed}
+		b.breakers[retailer.Name] = breaker
+	}
+	return breaker
+}
+
+func (b *Breakers) Allow(retailer *Retailer) BreakerDecision {
+	if b == nil {
+		return BreakerAllow
+	}
+
+	breaker := b.get(retailer)
+	switch breaker.State {
+	case BreakerOpen:
+		reopensAt := breaker.OpenedAt.Add(b.config.CoolDown)
+		if time.Now().Before(reopensAt) {
+			b.logger.Info(fmt.Sprintf("Skipping %s as its circuit breaker is open until %s", retailer.Name, reopensAt))
+			return BreakerSkip
+		}
+
+		b.transition(retailer, breaker, BreakerHalfOpen)
+		return BreakerProbe
+	case BreakerHalfOpen:
+		return BreakerProbe
+	default:
+		return BreakerAllow
+	}
+}
+
+// Probe closes a half-open breaker if the probe listing succeeded, or re-opens it otherwise
+func (b *Breakers) Probe(retailer *Retailer, succeeded bool) {
+	if b == nil {
+		return
+	}
+
+	breaker := b.get(retailer)
+	if succeeded {
+		b.transition(retailer, breaker, BreakerClosed)
+	} else {
+		breaker.OpenedAt = time.Now()
+		b.transition(retailer, breaker, BreakerOpen)
+	}
+}
+
+// Record opens a closed breaker if the retailer's failure rate this run reached the configured rate
+func (b *Breakers) Record(retailer *Retailer, succeeded, failed int) {
+	if b == nil || b.config.FailureRate <= 0 {
+		return
+	}
+
+	breaker := b.get(retailer)
+	requests := succeeded + failed
+	if breaker.State != BreakerClosed || requests == 0 || requests < b.config.MinRequests {
+		return
+	}
+
+	if float64(failed)/float64(requests) >= b.config.FailureRate {
+		breaker.OpenedAt = time.Now()
+		b.transition(retailer, breaker, BreakerOpen)
+	}
+}
+
+func (b *Breakers) transition(retailer *Retailer, breaker *Breaker, state BreakerState) {
+	if breaker.State == state {
+		return
+	}
+
+	b.logger.Info("Circuit breaker changed state", slog.String("retailer", retailer.Name), slog.String("from", string(breaker.State)), slog.String("to", string(state)))
+	breaker.State = state
+}
+
+// Save persists the breakers' states, unless the circuit breaker is disabled
+func (b *Breakers) Save(cache *Cache) error {
+	if b == nil || b.config.FailureRate <= 0 {
+		return nil
+	}
+
+	return cache.SetBreakers(b.breakers)
+}
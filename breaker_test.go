@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestBreakers(t *testing.T, cache *Cache) *Breakers {
+	breakers, err := LoadBreakers(cache, CircuitBreaker{FailureRate: 0.5, MinRequests: 2, CoolDown: time.Hour}, testLogger())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return breakers
+}
+
+func TestBreakers(t *testing.T) {
+	breakers := newTestBreakers(t, newTestCache(t))
+	retailer := &Retailer{Name: "Test Retailer"}
+
+	if decision := breakers.Allow(retailer); decision != BreakerAllow {
+		t.Fatalf("expected a new breaker to allow requests, got %v", decision)
+	}
+
+	// Too few requests to open the breaker
+	breakers.Record(retailer, 0, 1)
+	if state := breakers.get(retailer).State; state != BreakerClosed {
+		t.Fatalf("expected the breaker to stay closed, got %s", state)
+	}
+
+	// A failure rate under the configured rate keeps it closed
+	breakers.Record(retailer, 2, 1)
+	if state := breakers.get(retailer).State; state != BreakerClosed {
+		t.Fatalf("expected the breaker to stay closed, got %s", state)
+	}
+
+	breakers.Record(retailer, 1, 1)
+	if state := breakers.get(retailer).State; state != BreakerOpen {
+		t.Fatalf("expected the breaker to open, got %s", state)
+	}
+	if decision := breakers.Allow(retailer); decision != BreakerSkip {
+		t.Fatalf("expected an open breaker to skip requests, got %v", decision)
+	}
+
+	// Once the cool-down has passed a single listing is probed, and a failed probe re-opens the breaker
+	breakers.get(retailer).OpenedAt = time.Now().Add(-2 * time.Hour)
+	if decision := breakers.Allow(retailer); decision != BreakerProbe {
+		t.Fatalf("expected a cooled-down breaker to probe, got %v", decision)
+	}
+	if state := breakers.get(retailer).State; state != BreakerHalfOpen {
+		t.Fatalf("expected the breaker to be half-open, got %s", state)
+	}
+	breakers.Probe(retailer, false)
+	if state := breakers.get(retailer).State; state != BreakerOpen {
+		t.Fatalf("expected a failed probe to re-open the breaker, got %s", state)
+	}
+	if decision := breakers.Allow(retailer); decision != BreakerSkip {
+		t.Fatalf("expected a re-opened breaker to skip requests, got %v", decision)
+	}
+
+	// A successful probe closes the breaker
+	breakers.get(retailer).OpenedAt = time.Now().Add(-2 * time.Hour)
+	if decision := breakers.Allow(retailer); decision != BreakerProbe {
+		t.Fatalf("expected a cooled-down breaker to probe, got %v", decision)
+	}
+	breakers.Probe(retailer, true)
+	if decision := breakers.Allow(retailer); decision != BreakerAllow {
+		t.Fatalf("expected a closed breaker to allow requests, got %v", decision)
+	}
+}
+
+func TestBreakersSave(t *testing.T) {
+	cache := newTestCache(t)
+	breakers := newTestBreakers(t, cache)
+	retailer := &Retailer{Name: "Test Retailer"}
+
+	breakers.Record(retailer, 0, 2)
+	openedAt := breakers.get(retailer).OpenedAt
+	err := breakers.Save(cache)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded := newTestBreakers(t, cache)
+	breaker := loaded.get(retailer)
+	if breaker.State != BreakerOpen || breaker.OpenedAt.Unix() != openedAt.Unix() {
+		t.Errorf("unexpected breaker: %+v", breaker)
+	}
+	if decision := loaded.Allow(retailer); decision != BreakerSkip {
+		t.Errorf("expected a loaded open breaker to skip requests, got %v", decision)
+	}
+}
+
+func TestBreakersSaveDisabled(t *testing.T) {
+	cache := newTestCache(t)
+	breakers, err := LoadBreakers(cache, CircuitBreaker{}, testLogger())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	breakers.Allow(&Retailer{Name: "Test Retailer"})
+	err = breakers.Save(cache)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	saved, err := cache.GetBreakers()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(saved) != 0 {
+		t.Errorf("expected no breakers to be saved, got %d", len(saved))
+	}
+}
@@ -2,7 +2,12 @@ package main
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	_ "github.com/mattn/go-sqlite3"
+	"math"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -11,7 +16,33 @@ type Cache struct {
 }
 
 type CacheKey struct {
-	Retailer, Product string
+	Retailer, Product, Label string
+}
+
+type table struct {
+	name, schema string
+	// Whether the table is keyed by listing, so needs a label column for products with several links per retailer
+	listing bool
+}
+
+var tables = []table{
+	{"scrape_cache", "CREATE TABLE IF NOT EXISTS scrape_cache (provider TEXT, product TEXT, label TEXT NOT NULL DEFAULT '', price INTEGER, last_scrape INTEGER, PRIMARY KEY (provider, product, label))", true},
+	{"rejected_scrapes", "CREATE TABLE IF NOT EXISTS rejected_scrapes (provider TEXT, product TEXT, label TEXT NOT NULL DEFAULT '', url TEXT, price INTEGER, reason TEXT, rejected_at INTEGER)", true},
+	{"scrape_failures", "CREATE TABLE IF NOT EXISTS scrape_failures (provider TEXT, product TEXT, label TEXT NOT NULL DEFAULT '', url TEXT, error_class TEXT, error TEXT, failed_at INTEGER)", true},
+	{"retailer_outages", "CREATE TABLE IF NOT EXISTS retailer_outages (provider TEXT PRIMARY KEY, error_class TEXT, since INTEGER)", false},
+	{"circuit_breakers", "CREATE TABLE IF NOT EXISTS circuit_breakers (provider TEXT PRIMARY KEY, state TEXT, opened_at INTEGER)", false},
+	{"listing_pages", "CREATE TABLE IF NOT EXISTS listing_pages (provider TEXT, product TEXT, label TEXT NOT NULL DEFAULT '', first_title TEXT, last_url TEXT, last_title TEXT, issue TEXT, last_checked INTEGER, PRIMARY KEY (provider, product, label))", true},
+	{"canonical_urls", "CREATE TABLE IF NOT EXISTS canonical_urls (provider TEXT, product TEXT, label TEXT NOT NULL DEFAULT '', url TEXT, canonical_url TEXT, PRIMARY KEY (provider, product, label))", true},
+	{"short_links", "CREATE TABLE IF NOT EXISTS short_links (short_url TEXT PRIMARY KEY, resolved_url TEXT, resolved_at INTEGER)", false},
+	{"basket_totals", "CREATE TABLE IF NOT EXISTS basket_totals (name TEXT PRIMARY KEY, total INTEGER, notified_at INTEGER)", false},
+	{"listing_promotions", "CREATE TABLE IF NOT EXISTS listing_promotions (provider TEXT, product TEXT, label TEXT NOT NULL DEFAULT '', promotion TEXT, first_seen INTEGER, last_seen INTEGER, PRIMARY KEY (provider, product, label, promotion))", true},
+	{"price_history", "CREATE TABLE IF NOT EXISTS price_history (provider TEXT, product TEXT, label TEXT NOT NULL DEFAULT '', price INTEGER, scraped_at INTEGER)", true},
+	{"rule_matches", "CREATE TABLE IF NOT EXISTS rule_matches (rule TEXT, provider TEXT, product TEXT, label TEXT NOT NULL DEFAULT '', matched_at INTEGER, PRIMARY KEY (rule, provider, product, label))", true},
+	{"listing_failures", "CREATE TABLE IF NOT EXISTS listing_failures (provider TEXT, product TEXT, label TEXT NOT NULL DEFAULT '', error_class TEXT, last_error TEXT, consecutive_failures INTEGER, first_failure INTEGER, last_failure INTEGER, PRIMARY KEY (provider, product, label))", true},
+}
+
+var indexes = []string{
+	"CREATE INDEX IF NOT EXISTS price_history_listing ON price_history (provider, product, label, scraped_at)",
 }
 
 func NewCache(dbPath string) (*Cache, error) {
@@ -20,16 +51,77 @@ func NewCache(dbPath string) (*Cache, error) {
 		return nil, err
 	}
 
-	_, err = db.Exec("CREATE TABLE IF NOT EXISTS scrape_cache (provider TEXT, product TEXT, price INTEGER, last_scrape INTEGER, PRIMARY KEY (provider, product))")
-	if err != nil {
-		return nil, err
+	for _, t := range tables {
+		if t.listing {
+			err = addLabelColumn(db, t)
+			if err != nil {
+				return nil, fmt.Errorf("error adding label to %s: %v", t.name, err)
+			}
+		}
+
+		_, err = db.Exec(t.schema)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, index := range indexes {
+		_, err = db.Exec(index)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return &Cache{db: db}, nil
 }
 
+// addLabelColumn rebuilds a listing table created before listings had labels, as the label is part of its key
+func addLabelColumn(db *sql.DB, t table) error {
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?)", t.name)
+	if err != nil {
+		return err
+	}
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		err = rows.Scan(&column)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		columns = append(columns, column)
+	}
+	rows.Close()
+
+	if len(columns) == 0 || slices.Contains(columns, "label") {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	columnList := strings.Join(columns, ", ")
+	for _, statement := range []string{
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s_old", t.name, t.name),
+		t.schema,
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s_old", t.name, columnList, columnList, t.name),
+		fmt.Sprintf("DROP TABLE %s_old", t.name),
+	} {
+		_, err = tx.Exec(statement)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
 func (c *Cache) GetScrapes() (map[CacheKey]float64, error) {
-	rows, err := c.db.Query("SELECT provider, product, price FROM scrape_cache")
+	rows, err := c.db.Query("SELECT provider, product, label, price FROM scrape_cache")
 	if err != nil {
 		return nil, err
 	}
@@ -38,10 +130,10 @@ func (c *Cache) GetScrapes() (map[CacheKey]float64, error) {
 	scrapes := make(map[CacheKey]float64)
 	for rows.Next() {
 		var (
-			provider, product string
-			price             int
+			provider, product, label string
+			price                    int
 		)
-		err = rows.Scan(&provider, &product, &price)
+		err = rows.Scan(&provider, &product, &label, &price)
 		if err != nil {
 			return nil, err
 		}
@@ -49,6 +141,7 @@ func (c *Cache) GetScrapes() (map[CacheKey]float64, error) {
 		scrapes[CacheKey{
 			Retailer: provider,
 			Product:  product,
+			Label:    label,
 		}] = float64(price) / 100
 	}
 
@@ -61,14 +154,503 @@ func (c *Cache) SetScrapes(scrapes map[*Product][]SuccessScrape) error {
 		return err
 	}
 
-	stmt, err := tx.Prepare("INSERT OR REPLACE INTO scrape_cache (provider, product, price, last_scrape) VALUES (?, ?, ?, ?)")
+	stmt, err := tx.Prepare("INSERT OR REPLACE INTO scrape_cache (provider, product, label, price, last_scrape) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+
+	historyStmt, err := tx.Prepare("INSERT INTO price_history (provider, product, label, price, scraped_at) VALUES (?, ?, ?, ?, ?)")
 	if err != nil {
 		return err
 	}
 
 	for product, successScrapes := range scrapes {
 		for _, successScrape := range successScrapes {
-			_, err = stmt.Exec(successScrape.Retailer.Name, product.Name, int(successScrape.Price*100), time.Now().Unix())
+			_, err = stmt.Exec(successScrape.Retailer.Name, product.Name, successScrape.Label, int(successScrape.Price*100), time.Now().Unix())
+			if err != nil {
+				return err
+			}
+
+			_, err = historyStmt.Exec(successScrape.Retailer.Name, product.Name, successScrape.Label, int(successScrape.Price*100), time.Now().Unix())
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Prices older than the history window are only needed for each listing's lowest ever price, so keep just that row
+	_, err = tx.Exec(`DELETE FROM price_history WHERE scraped_at < ? AND rowid NOT IN (
+			SELECT MIN(rowid) FROM price_history AS h WHERE price = (
+				SELECT MIN(price) FROM price_history WHERE provider = h.provider AND product = h.product AND label = h.label
+			) GROUP BY provider, product, label
+		)`, time.Now().Add(-priceHistoryWindow).Unix())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (c *Cache) AddRejectedScrapes(rejected []RejectedScrape) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM rejected_scrapes WHERE rejected_at < ?", time.Now().Add(-rejectedScrapeRetention).Unix())
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO rejected_scrapes (provider, product, label, url, price, reason, rejected_at) VALUES (?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+
+	for _, r := range rejected {
+		_, err = stmt.Exec(r.Retailer.Name, r.Product.Name, r.Label, r.Url, int(r.Price*100), r.Reason, time.Now().Unix())
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetFailureCounts returns the number of consecutive runs each failing listing has failed
+func (c *Cache) GetFailureCounts() (map[CacheKey]int, error) {
+	rows, err := c.db.Query("SELECT provider, product, label, consecutive_failures FROM listing_failures")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[CacheKey]int)
+	for rows.Next() {
+		var (
+			provider, product, label string
+			count                    int
+		)
+		err = rows.Scan(&provider, &product, &label, &count)
+		if err != nil {
+			return nil, err
+		}
+
+		counts[CacheKey{Retailer: provider, Product: product, Label: label}] = count
+	}
+
+	return counts, nil
+}
+
+// SetFailures stores this run's failures, counting each listing's consecutive failures, and resets the counts of
+// the listings that were scraped successfully
+func (c *Cache) SetFailures(update FailureUpdate) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	historyStmt, err := tx.Prepare("INSERT INTO scrape_failures (provider, product, label, url, error_class, error, failed_at) VALUES (?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+
+	countStmt, err := tx.Prepare(`INSERT INTO listing_failures (provider, product, label, error_class, last_error, consecutive_failures, first_failure, last_failure) VALUES (?, ?, ?, ?, ?, 1, ?, ?)
+		ON CONFLICT (provider, product, label) DO UPDATE SET error_class = excluded.error_class, last_error = excluded.last_error, consecutive_failures = consecutive_failures + 1, last_failure = excluded.last_failure`)
+	if err != nil {
+		return err
+	}
+
+	clearStmt, err := tx.Prepare("DELETE FROM listing_failures WHERE provider = ? AND product = ? AND label = ?")
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	for _, failure := range update.Failures {
+		class := string(ClassifyError(failure.Error))
+
+		_, err = historyStmt.Exec(failure.Retailer.Name, failure.Product.Name, failure.Label, failure.Url, class, failure.Error.Error(), now)
+		if err != nil {
+			return err
+		}
+
+		_, err = countStmt.Exec(failure.Retailer.Name, failure.Product.Name, failure.Label, class, failure.Error.Error(), now, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, key := range update.Succeeded {
+		_, err = clearStmt.Exec(key.Retailer, key.Product, key.Label)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (c *Cache) GetOutages() (map[string]ErrorClass, error) {
+	rows, err := c.db.Query("SELECT provider, error_class FROM retailer_outages")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	outages := make(map[string]ErrorClass)
+	for rows.Next() {
+		var provider, class string
+		err = rows.Scan(&provider, &class)
+		if err != nil {
+			return nil, err
+		}
+
+		outages[provider] = ErrorClass(class)
+	}
+
+	return outages, nil
+}
+
+func (c *Cache) SetOutages(started []RetailerOutage, recovered []*Retailer) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, outage := range started {
+		_, err = tx.Exec("INSERT OR REPLACE INTO retailer_outages (provider, error_class, since) VALUES (?, ?, ?)", outage.Retailer.Name, string(outage.Class), time.Now().Unix())
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, retailer := range recovered {
+		_, err = tx.Exec("DELETE FROM retailer_outages WHERE provider = ?", retailer.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (c *Cache) GetBreakers() (map[string]*Breaker, error) {
+	rows, err := c.db.Query("SELECT provider, state, opened_at FROM circuit_breakers")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	breakers := make(map[string]*Breaker)
+	for rows.Next() {
+		var (
+			provider, state string
+			openedAt        int64
+		)
+		err = rows.Scan(&provider, &state, &openedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		breakers[provider] = &Breaker{State: BreakerState(state), OpenedAt: time.Unix(openedAt, 0)}
+	}
+
+	return breakers, nil
+}
+
+func (c *Cache) SetBreakers(breakers map[string]*Breaker) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT OR REPLACE INTO circuit_breakers (provider, state, opened_at) VALUES (?, ?, ?)")
+	if err != nil {
+		return err
+	}
+
+	for provider, breaker := range breakers {
+		_, err = stmt.Exec(provider, string(breaker.State), breaker.OpenedAt.Unix())
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (c *Cache) GetPageRecords() (map[CacheKey]PageRecord, error) {
+	rows, err := c.db.Query("SELECT provider, product, label, first_title, issue FROM listing_pages")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make(map[CacheKey]PageRecord)
+	for rows.Next() {
+		var provider, product, label, firstTitle, issue string
+		err = rows.Scan(&provider, &product, &label, &firstTitle, &issue)
+		if err != nil {
+			return nil, err
+		}
+
+		records[CacheKey{Retailer: provider, Product: product, Label: label}] = PageRecord{FirstTitle: firstTitle, Issue: HealthIssue(issue)}
+	}
+
+	return records, nil
+}
+
+func (c *Cache) SetPageRecords(updates map[CacheKey]PageUpdate) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// A failed scrape has no page details, so keep those from the last successful one
+	stmt, err := tx.Prepare(`INSERT INTO listing_pages (provider, product, label, first_title, last_url, last_title, issue, last_checked) VALUES (?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?)
+		ON CONFLICT (provider, product, label) DO UPDATE SET first_title = excluded.first_title, last_url = COALESCE(excluded.last_url, last_url), last_title = COALESCE(excluded.last_title, last_title), issue = excluded.issue, last_checked = excluded.last_checked`)
+	if err != nil {
+		return err
+	}
+
+	for key, update := range updates {
+		_, err = stmt.Exec(key.Retailer, key.Product, key.Label, update.FirstTitle, update.FinalUrl, update.Title, string(update.Issue), time.Now().Unix())
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (c *Cache) GetCanonicalUrls() (map[CacheKey]CanonicalListing, error) {
+	rows, err := c.db.Query("SELECT provider, product, label, url, canonical_url FROM canonical_urls")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	listings := make(map[CacheKey]CanonicalListing)
+	for rows.Next() {
+		var provider, product, label, link, canonicalUrl string
+		err = rows.Scan(&provider, &product, &label, &link, &canonicalUrl)
+		if err != nil {
+			return nil, err
+		}
+
+		listings[CacheKey{Retailer: provider, Product: product, Label: label}] = CanonicalListing{Url: link, CanonicalUrl: canonicalUrl}
+	}
+
+	return listings, nil
+}
+
+func (c *Cache) SetCanonicalUrls(listings map[CacheKey]CanonicalListing) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT OR REPLACE INTO canonical_urls (provider, product, label, url, canonical_url) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+
+	for key, listing := range listings {
+		_, err = stmt.Exec(key.Retailer, key.Product, key.Label, listing.Url, listing.CanonicalUrl)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (c *Cache) GetShortLink(shortUrl string) (string, error) {
+	var resolved string
+	err := c.db.QueryRow("SELECT resolved_url FROM short_links WHERE short_url = ?", shortUrl).Scan(&resolved)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return resolved, err
+}
+
+func (c *Cache) SetShortLink(shortUrl, resolved string) error {
+	_, err := c.db.Exec("INSERT OR REPLACE INTO short_links (short_url, resolved_url, resolved_at) VALUES (?, ?, ?)", shortUrl, resolved, time.Now().Unix())
+	return err
+}
+
+func (c *Cache) GetBasketTotals() (map[string]float64, error) {
+	rows, err := c.db.Query("SELECT name, total FROM basket_totals")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[string]float64)
+	for rows.Next() {
+		var (
+			name  string
+			total int
+		)
+		err = rows.Scan(&name, &total)
+		if err != nil {
+			return nil, err
+		}
+
+		totals[name] = float64(total) / 100
+	}
+
+	return totals, nil
+}
+
+func (c *Cache) SetBasketTotal(name string, total float64) error {
+	_, err := c.db.Exec("INSERT OR REPLACE INTO basket_totals (name, total, notified_at) VALUES (?, ?, ?)", name, int(math.Round(total*100)), time.Now().Unix())
+	return err
+}
+
+func (c *Cache) GetPromotions() (map[CacheKey]map[string]bool, error) {
+	rows, err := c.db.Query("SELECT provider, product, label, promotion FROM listing_promotions")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	promotions := make(map[CacheKey]map[string]bool)
+	for rows.Next() {
+		var provider, product, label, promotion string
+		err = rows.Scan(&provider, &product, &label, &promotion)
+		if err != nil {
+			return nil, err
+		}
+
+		key := CacheKey{Retailer: provider, Product: product, Label: label}
+		if promotions[key] == nil {
+			promotions[key] = make(map[string]bool)
+		}
+		promotions[key][promotion] = true
+	}
+
+	return promotions, nil
+}
+
+// SetPromotions replaces the promotions stored for each listing, keeping when each was first seen. An empty
+// promotion is stored for every listing, so a listing that has been checked can be told apart from a new one.
+func (c *Cache) SetPromotions(promotions map[CacheKey][]string) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Unix()
+	for key, texts := range promotions {
+		texts = append([]string{""}, texts...)
+		for _, text := range texts {
+			_, err = tx.Exec(`INSERT INTO listing_promotions (provider, product, label, promotion, first_seen, last_seen) VALUES (?, ?, ?, ?, ?, ?)
+				ON CONFLICT (provider, product, label, promotion) DO UPDATE SET last_seen = excluded.last_seen`,
+				key.Retailer, key.Product, key.Label, text, now, now)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Remove the promotions no longer shown on the listing
+		query := "DELETE FROM listing_promotions WHERE provider = ? AND product = ? AND label = ? AND promotion NOT IN (?" + strings.Repeat(", ?", len(texts)-1) + ")"
+		args := []interface{}{key.Retailer, key.Product, key.Label}
+		for _, text := range texts {
+			args = append(args, text)
+		}
+
+		_, err = tx.Exec(query, args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetPriceStats summarises each listing's price history, both since the given time and over all time
+func (c *Cache) GetPriceStats(since time.Time) (map[CacheKey]PriceStats, error) {
+	rows, err := c.db.Query(`SELECT provider, product, label,
+			COALESCE(MIN(CASE WHEN scraped_at >= ? THEN price END), 0),
+			COALESCE(MAX(CASE WHEN scraped_at >= ? THEN price END), 0),
+			COALESCE(AVG(CASE WHEN scraped_at >= ? THEN price END), 0),
+			MIN(price)
+		FROM price_history GROUP BY provider, product, label`, since.Unix(), since.Unix(), since.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make(map[CacheKey]PriceStats)
+	for rows.Next() {
+		var (
+			provider, product, label             string
+			lowest, highest, average, lowestEver float64
+		)
+		err = rows.Scan(&provider, &product, &label, &lowest, &highest, &average, &lowestEver)
+		if err != nil {
+			return nil, err
+		}
+
+		stats[CacheKey{Retailer: provider, Product: product, Label: label}] = PriceStats{
+			Lowest90d:  lowest / 100,
+			Highest90d: highest / 100,
+			Average90d: math.Round(average) / 100,
+			LowestEver: lowestEver / 100,
+		}
+	}
+
+	return stats, nil
+}
+
+func (c *Cache) GetRuleMatches() (map[string]map[CacheKey]bool, error) {
+	rows, err := c.db.Query("SELECT rule, provider, product, label FROM rule_matches")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := make(map[string]map[CacheKey]bool)
+	for rows.Next() {
+		var rule, provider, product, label string
+		err = rows.Scan(&rule, &provider, &product, &label)
+		if err != nil {
+			return nil, err
+		}
+
+		if matches[rule] == nil {
+			matches[rule] = make(map[CacheKey]bool)
+		}
+		matches[rule][CacheKey{Retailer: provider, Product: product, Label: label}] = true
+	}
+
+	return matches, nil
+}
+
+// SetRuleMatches stores whether each listing matched each rule, keeping when the match started
+func (c *Cache) SetRuleMatches(results map[string]map[CacheKey]bool) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for rule, listings := range results {
+		for key, matched := range listings {
+			if matched {
+				_, err = tx.Exec("INSERT OR IGNORE INTO rule_matches (rule, provider, product, label, matched_at) VALUES (?, ?, ?, ?, ?)", rule, key.Retailer, key.Product, key.Label, time.Now().Unix())
+			} else {
+				_, err = tx.Exec("DELETE FROM rule_matches WHERE rule = ? AND provider = ? AND product = ? AND label = ?", rule, key.Retailer, key.Product, key.Label)
+			}
 			if err != nil {
 				return err
 			}
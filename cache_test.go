@@ -0,0 +1,56 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewCacheAddsLabels(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	// Create a cache as it was before listings had labels
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = db.Exec("CREATE TABLE scrape_cache (provider TEXT, product TEXT, price INTEGER, last_scrape INTEGER, PRIMARY KEY (provider, product))")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = db.Exec("INSERT INTO scrape_cache (provider, product, price, last_scrape) VALUES ('Boots', 'Test Product', 1099, 0)")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db.Close()
+
+	cache, err := NewCache(dbPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	retailer := &Retailer{Name: "Boots"}
+	product := &Product{Name: "Test Product"}
+	err = cache.SetScrapes(map[*Product][]SuccessScrape{product: {{Retailer: retailer, Price: 9.99, Label: "Exclusive"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	scrapes, err := cache.GetScrapes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[CacheKey]float64{
+		{Retailer: "Boots", Product: "Test Product"}:                     10.99,
+		{Retailer: "Boots", Product: "Test Product", Label: "Exclusive"}: 9.99,
+	}
+	if len(scrapes) != len(expected) {
+		t.Fatalf("unexpected length: expected %d, got %d", len(expected), len(scrapes))
+	}
+	for key, price := range expected {
+		if scrapes[key] != price {
+			t.Errorf("unexpected price for %+v: expected %.2f, got %.2f", key, price, scrapes[key])
+		}
+	}
+}
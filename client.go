@@ -7,14 +7,21 @@ import (
 )
 
 type Client interface {
-	SendMessage(markdown string) error
+	Notify(ctx context.Context, notification Notification) error
 	Stop() error
 }
 
-type DefaultClient struct{}
+type DefaultClient struct {
+	templates *Templates
+}
+
+func (d *DefaultClient) Notify(ctx context.Context, notification Notification) error {
+	message, err := d.templates.RenderMarkdown(notification)
+	if err != nil {
+		return err
+	}
 
-func (d *DefaultClient) SendMessage(markdown string) error {
-	_, err := fmt.Println(markdown)
+	_, err = fmt.Println(message)
 	return err
 }
 
@@ -23,9 +30,48 @@ func (d *DefaultClient) Stop() error {
 }
 
 func getClient(ctx context.Context, logger *slog.Logger, config Config) (Client, error) {
-	if config.Matrix == nil {
-		return &DefaultClient{}, nil
-	} else {
-		return connectToMatrix(ctx, logger, *config.Matrix, config.General.Database)
+	templates, err := NewTemplates(config.Templates)
+	if err != nil {
+		return nil, err
+	}
+
+	composite := &CompositeClient{logger: logger}
+
+	// Without any notifiers configured, notifications go to the console and to Matrix if the [matrix] settings are given
+	if len(config.Notifiers) == 0 {
+		composite.Add("console", &DefaultClient{templates: templates})
+		if config.Matrix != nil {
+			client, err := connectToMatrix(ctx, logger, *config.Matrix, config.General.Database, templates)
+			if err != nil {
+				return nil, err
+			}
+			composite.Add("matrix", client)
+		}
+		return composite, nil
 	}
+
+	for i, notifier := range config.Notifiers {
+		name := notifier.Name
+		if name == "" {
+			name = fmt.Sprintf("%s %d", notifier.Type, i+1)
+		}
+
+		if notifier.Enabled != nil && !*notifier.Enabled {
+			logger.Info("Notifier disabled", slog.String("notifier", name))
+			continue
+		}
+
+		client, err := newNotifier(ctx, logger, config, notifier, templates)
+		if err != nil {
+			_ = composite.Stop()
+			return nil, fmt.Errorf("error creating notifier %s: %v", name, err)
+		}
+
+		composite.Add(name, &FilteredClient{
+			Client: client,
+			filter: NotifierFilter{Categories: notifier.Categories, MinDiscount: float64(notifier.MinDiscount)},
+		})
+	}
+
+	return composite, nil
 }
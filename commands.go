@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+func runCommand(ctx context.Context, args []string, config Config, cache *Cache, products Products, baskets []Basket) error {
+	switch args[0] {
+	case "basket":
+		return basketCommand(ctx, args[1:], cache, baskets)
+	case "preview":
+		return previewCommand(ctx, args[1:], config, cache, products)
+	default:
+		return fmt.Errorf("unknown command %s", args[0])
+	}
+}
+
+// basketCommand prints the cheapest way to buy the named basket, or every basket if no name is given
+func basketCommand(ctx context.Context, args []string, cache *Cache, baskets []Basket) error {
+	if len(args) > 0 {
+		basket, err := FindBasket(baskets, args[0])
+		if err != nil {
+			return err
+		}
+		baskets = []Basket{*basket}
+	}
+
+	cachedPrices, err := cache.GetScrapes()
+	if err != nil {
+		return fmt.Errorf("error getting cached prices: %v", err)
+	}
+
+	client := &DefaultClient{}
+	for i := range baskets {
+		err = notifyBasket(ctx, baskets[i].Optimise(cachedPrices), client)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// previewCommand prints the deals message for the cached prices that are deals using the configured templates, to try
+// them out without waiting for a new deal. With --all every cached price is included.
+func previewCommand(ctx context.Context, args []string, config Config, cache *Cache, products Products) error {
+	all := len(args) > 0 && args[0] == "--all"
+	templates, err := NewTemplates(config.Templates)
+	if err != nil {
+		return err
+	}
+
+	cachedPrices, err := cache.GetScrapes()
+	if err != nil {
+		return fmt.Errorf("error getting cached prices: %v", err)
+	}
+
+	prices := make(map[*Product][]SuccessScrape)
+	for i := range products {
+		product := &products[i]
+		for _, link := range product.Links {
+			price, ok := cachedPrices[CacheKey{Retailer: link.Retailer.Name, Product: product.Name, Label: link.Label}]
+			if !ok {
+				continue
+			}
+
+			prices[product] = append(prices[product], SuccessScrape{
+				Retailer: link.Retailer,
+				Price:    price,
+				Url:      link.Url,
+				Label:    link.Label,
+				Size:     link.Size,
+				Unit:     link.Unit,
+			})
+		}
+	}
+
+	if len(prices) == 0 {
+		return fmt.Errorf("no cached prices to preview, run a scrape first")
+	}
+
+	// Without a previous price to compare against, each price is judged as if it was first seen
+	if !all {
+		prices = GetNotifiablePrices(prices, config.General.MinDiscount, config.General.UseEffectivePrice)
+		if len(prices) == 0 {
+			return fmt.Errorf("none of the cached prices are deals, use --all to preview every price")
+		}
+	}
+
+	return notify(ctx, prices, &DefaultClient{templates: templates})
+}
@@ -1,21 +1,37 @@
 package main
 
 import (
+	"fmt"
 	"github.com/pelletier/go-toml"
 	"os"
+	"strconv"
 	"time"
 )
 
 type Config struct {
-	General  General       `toml:"general"`
-	Matrix   *Matrix       `toml:"matrix"`
-	Products []ProductTOML `toml:"products"`
+	General        General                    `toml:"general"`
+	Matrix         *Matrix                    `toml:"matrix"`
+	Notifiers      []NotifierTOML             `toml:"notifiers"`
+	Sanity         Sanity                     `toml:"sanity"`
+	Outages        Outages                    `toml:"outages"`
+	CircuitBreaker CircuitBreaker             `toml:"circuit_breaker"`
+	Retailers      map[string]RetailerTOML    `toml:"retailers"`
+	Categories     map[string]NotifyRulesTOML `toml:"categories"`
+	Products       []ProductTOML              `toml:"products"`
+	Baskets        []BasketTOML               `toml:"baskets"`
+	Rules          []AlertRuleTOML            `toml:"rules"`
+	Templates      TemplatesTOML              `toml:"templates"`
 }
 
 type General struct {
-	Database    string        `toml:"database"`
-	Interval    time.Duration `toml:"interval"`
-	MinDiscount float64       `toml:"min_discount"`
+	Database              string        `toml:"database"`
+	Interval              time.Duration `toml:"interval"`
+	MinDiscount           float64       `toml:"min_discount"`
+	FailureAlertThreshold int           `toml:"failure_alert_threshold"`
+	ShortLinkHosts        []string      `toml:"short_link_hosts"`
+	UseEffectivePrice     bool          `toml:"use_effective_price"`
+	// Only notify the listings matching the alert rules, rather than discounts against the base price
+	RulesOnly bool `toml:"rules_only"`
 }
 
 type Matrix struct {
@@ -25,11 +41,199 @@ type Matrix struct {
 	RoomID      string `toml:"room_id"`
 }
 
+type NotifierTOML struct {
+	Type        string       `toml:"type"`
+	Name        string       `toml:"name"`
+	Enabled     *bool        `toml:"enabled"`
+	Categories  []string     `toml:"categories"`
+	MinDiscount Number       `toml:"min_discount"`
+	Matrix      *Matrix      `toml:"matrix"`
+	Webhook     *Webhook     `toml:"webhook"`
+	Email       *Email       `toml:"email"`
+	Discord     *ChatWebhook `toml:"discord"`
+	Slack       *ChatWebhook `toml:"slack"`
+	Ntfy        *Push        `toml:"ntfy"`
+	Gotify      *Push        `toml:"gotify"`
+	Telegram    *Telegram    `toml:"telegram"`
+}
+
+type Email struct {
+	Host string `toml:"host"`
+	Port int    `toml:"port"`
+	// One of starttls (the default), tls or none
+	Security string   `toml:"security"`
+	Username string   `toml:"username"`
+	Password string   `toml:"password"`
+	From     string   `toml:"from"`
+	To       []string `toml:"to"`
+}
+
+type Webhook struct {
+	Url     string            `toml:"url"`
+	Headers map[string]string `toml:"headers"`
+	Secret  string            `toml:"secret"`
+}
+
+// ChatWebhook is an incoming webhook for a chat service, such as Discord or Slack
+type ChatWebhook struct {
+	Url string `toml:"url"`
+}
+
+// Push is a push notification service, such as ntfy or Gotify
+type Push struct {
+	Server string `toml:"server"`
+	// The ntfy topic to publish to
+	Topic string `toml:"topic"`
+	Token string `toml:"token"`
+	// The emoji shown for each category, e.g. { skincare = "🧴" }
+	Emoji map[string]string `toml:"emoji"`
+}
+
+type Telegram struct {
+	Token string `toml:"token"`
+	// A numeric chat ID, or @username for a public channel
+	ChatID string `toml:"chat_id"`
+	ApiUrl string `toml:"api_url"`
+}
+
+type Sanity struct {
+	MaxDrop      float64       `toml:"max_drop"`
+	MinPrice     float64       `toml:"min_price"`
+	ConfirmDelay time.Duration `toml:"confirm_delay"`
+}
+
+type Outages struct {
+	MinListings  int     `toml:"min_listings"`
+	FailureRatio float64 `toml:"failure_ratio"`
+}
+
+type CircuitBreaker struct {
+	FailureRate float64       `toml:"failure_rate"`
+	MinRequests int           `toml:"min_requests"`
+	CoolDown    time.Duration `toml:"cool_down"`
+}
+
+type RetailerTOML struct {
+	DeliveryCharge        Number `toml:"delivery_charge"`
+	FreeDeliveryThreshold Number `toml:"free_delivery_threshold"`
+	ClickAndCollect       bool   `toml:"click_and_collect"`
+	CollectionFee         Number `toml:"collection_fee"`
+	Member                bool   `toml:"member"`
+}
+
+// TemplatesTOML overrides the Go templates used to write the deals message, with the defaults used for any left out
+type TemplatesTOML struct {
+	Message string `toml:"message"`
+	Product string `toml:"product"`
+	Price   string `toml:"price"`
+}
+
+type AlertRuleTOML struct {
+	Name string `toml:"name"`
+	When string `toml:"when"`
+}
+
+type BasketTOML struct {
+	Name     string   `toml:"name"`
+	Products []string `toml:"products"`
+	Notify   bool     `toml:"notify"`
+}
+
+type NotifyRulesTOML struct {
+	MinDiscount     *Number  `toml:"min_discount"`
+	TargetPrice     Number   `toml:"target_price"`
+	NotifyRetailers []string `toml:"notify_retailers"`
+}
+
 type ProductTOML struct {
-	Name      string            `toml:"name"`
-	BasePrice float64           `toml:"base_price"`
-	Category  string            `toml:"category"`
-	Links     map[string]string `toml:"links"`
+	Name         string  `toml:"name"`
+	BasePrice    float64 `toml:"base_price"`
+	Category     string  `toml:"category"`
+	Size         Number  `toml:"size"`
+	Unit         string  `toml:"unit"`
+	SellerPolicy string  `toml:"seller_policy"`
+	// Notification rules overriding the category's and general settings
+	MinDiscount     *Number                `toml:"min_discount"`
+	TargetPrice     Number                 `toml:"target_price"`
+	NotifyRetailers []string               `toml:"notify_retailers"`
+	Links           map[string]interface{} `toml:"links"`
+}
+
+type LinkTOML struct {
+	Url   string `toml:"url"`
+	Label string `toml:"label"`
+	Size  Number `toml:"size"`
+	Unit  string `toml:"unit"`
+}
+
+// Number accepts both integers and floats, e.g. `size = 50` as well as `size = 50.0`
+type Number float64
+
+func (n *Number) UnmarshalTOML(value interface{}) error {
+	switch v := value.(type) {
+	case int64:
+		*n = Number(v)
+	case float64:
+		*n = Number(v)
+	default:
+		return fmt.Errorf("expected a number, got %T", value)
+	}
+	return nil
+}
+
+// GetLinks returns the links for each retailer, which can be configured as a URL, a list of URLs, or a list of
+// tables with a URL and label. Unlabelled links in a list are labelled with their position in it.
+func (p ProductTOML) GetLinks() (map[string][]LinkTOML, error) {
+	links := make(map[string][]LinkTOML)
+
+	for retailer, value := range p.Links {
+		var values []interface{}
+		switch v := value.(type) {
+		case []interface{}:
+			values = v
+		case []map[string]interface{}:
+			for _, m := range v {
+				values = append(values, m)
+			}
+		default:
+			values = []interface{}{v}
+		}
+
+		for _, value := range values {
+			switch v := value.(type) {
+			case string:
+				links[retailer] = append(links[retailer], LinkTOML{Url: v})
+			case map[string]interface{}:
+				tree, err := toml.TreeFromMap(v)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s link for %s: %v", retailer, p.Name, err)
+				}
+
+				var link LinkTOML
+				err = tree.Unmarshal(&link)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s link for %s: %v", retailer, p.Name, err)
+				}
+				if link.Url == "" {
+					return nil, fmt.Errorf("%s link for %s is missing a url", retailer, p.Name)
+				}
+
+				links[retailer] = append(links[retailer], link)
+			default:
+				return nil, fmt.Errorf("invalid %s link for %s: unexpected %T", retailer, p.Name, v)
+			}
+		}
+
+		if retailerLinks := links[retailer]; len(retailerLinks) > 1 {
+			for i := range retailerLinks {
+				if retailerLinks[i].Label == "" {
+					retailerLinks[i].Label = strconv.Itoa(i + 1)
+				}
+			}
+		}
+	}
+
+	return links, nil
 }
 
 func loadConfig() (Config, error) {
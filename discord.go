@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits on Discord messages, counted in characters
+const (
+	discordMaxEmbeds       = 10
+	discordMaxMessageChars = 6000
+	discordMaxContent      = 2000
+	discordMaxTitle        = 256
+	discordMaxDescription  = 4096
+	discordMaxFields       = 25
+	discordMaxFieldName    = 256
+	discordMaxFieldValue   = 1024
+	discordDealColour      = 0x2ecc71
+)
+
+type discordMessage struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title,omitempty"`
+	Url         string         `json:"url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color,omitempty"`
+	Fields      []discordField `json:"fields,omitempty"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+func (e discordEmbed) length() int {
+	length := utf8.RuneCountInString(e.Title) + utf8.RuneCountInString(e.Description)
+	for _, field := range e.Fields {
+		length += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+	}
+	return length
+}
+
+// DiscordClient posts notifications to a Discord incoming webhook, with an embed for each product
+type DiscordClient struct {
+	client *http.Client
+	url    string
+}
+
+func NewDiscordClient(config ChatWebhook) (*DiscordClient, error) {
+	if config.Url == "" {
+		return nil, fmt.Errorf("discord webhook url is required")
+	}
+
+	return &DiscordClient{
+		client: &http.Client{Timeout: 30 * time.Second},
+		url:    config.Url,
+	}, nil
+}
+
+func (d *DiscordClient) Notify(ctx context.Context, notification Notification) error {
+	for _, message := range newDiscordMessages(notification) {
+		body, err := json.Marshal(message)
+		if err != nil {
+			return fmt.Errorf("error encoding message: %v", err)
+		}
+
+		err = postJSON(ctx, d.client, d.url, body, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *DiscordClient) Stop() error {
+	return nil
+}
+
+// newDiscordMessages builds the embeds for a notification, split into as many messages as Discord's limits need
+func newDiscordMessages(notification Notification) []discordMessage {
+	notification = escapeMarkdown(notification)
+
+	var embeds []discordEmbed
+
+	for _, category := range notification.Categories {
+		for _, deal := range category.Deals {
+			embeds = append(embeds, newDiscordDealEmbed(category.Name, deal))
+		}
+	}
+
+	for _, section := range notification.Sections {
+		embeds = append(embeds, newDiscordSectionEmbed(section))
+	}
+
+	if notification.Summary != "" {
+		embeds = append(embeds, discordEmbed{Description: truncateText("**"+notification.Summary+"**", discordMaxDescription)})
+	}
+
+	content := truncateText(fmt.Sprintf("%s **%s**", notification.Icon, notification.Title), discordMaxContent)
+	messages := []discordMessage{{Content: content}}
+	length := utf8.RuneCountInString(content)
+
+	for _, embed := range embeds {
+		current := &messages[len(messages)-1]
+		if len(current.Embeds) == discordMaxEmbeds || (len(current.Embeds) > 0 && length+embed.length() > discordMaxMessageChars) {
+			messages = append(messages, discordMessage{})
+			current = &messages[len(messages)-1]
+			length = 0
+		}
+
+		current.Embeds = append(current.Embeds, embed)
+		length += embed.length()
+	}
+
+	return messages
+}
+
+func newDiscordDealEmbed(category string, deal Deal) discordEmbed {
+	embed := discordEmbed{
+		Title:       truncateText(deal.Product, discordMaxTitle),
+		Url:         deal.Prices[0].Url,
+		Description: fmt.Sprintf("%s\nBase price: £%.2f", category, deal.BasePrice),
+		Color:       discordDealColour,
+	}
+
+	for i, price := range deal.Prices {
+		if i == discordMaxFields {
+			break
+		}
+
+		value := fmt.Sprintf("£%.2f", price.Price)
+		if i == 0 {
+			value = "**" + value + "**"
+		}
+		value += fmt.Sprintf("%s (%s)\n[View listing](%s)", priceExtras(price), discountText(price), price.Url)
+
+		embed.Fields = append(embed.Fields, discordField{
+			Name:   truncateText(priceChangeMarker(price.Change)+price.Listing, discordMaxFieldName),
+			Value:  truncateText(value, discordMaxFieldValue),
+			Inline: true,
+		})
+	}
+
+	return embed
+}
+
+func newDiscordSectionEmbed(section Section) discordEmbed {
+	var description strings.Builder
+	for _, item := range section.Items {
+		fmt.Fprintf(&description, "- **%s**", item.Name)
+		if item.Listing != "" {
+			fmt.Fprintf(&description, " at [%s](%s)", item.Listing, item.Url)
+		}
+		if item.Detail != "" {
+			fmt.Fprintf(&description, "%s%s", section.detailSeparator(), item.Detail)
+		}
+		description.WriteString("\n")
+	}
+	if section.Footer != "" {
+		description.WriteString(section.Footer)
+	}
+
+	title := section.Title
+	if section.Icon != "" && title != "" {
+		title = section.Icon + " " + title
+	}
+
+	return discordEmbed{
+		Title:       truncateText(title, discordMaxTitle),
+		Description: truncateText(strings.TrimSpace(description.String()), discordMaxDescription),
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDiscordClient(t *testing.T) {
+	var messages []discordMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message discordMessage
+		err := json.NewDecoder(r.Body).Decode(&message)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		messages = append(messages, message)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client, err := NewDiscordClient(ChatWebhook{Url: server.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	retailer := &Retailer{Name: "Test Retailer"}
+	otherRetailer := &Retailer{Name: "Other Retailer"}
+	prices := map[*Product][]SuccessScrape{
+		&Product{Name: "Serum", BasePrice: 10.00, Category: "skincare"}: {
+			{Retailer: retailer, Price: 7.00, Url: "https://test.com/1", CachedPrice: floatPtr(8.00)},
+			{Retailer: otherRetailer, Price: 8.00, Url: "https://other.com/1"},
+		},
+	}
+
+	err = notify(context.Background(), prices, client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(messages) != 1 || len(messages[0].Embeds) != 1 {
+		t.Fatalf("unexpected messages: %+v", messages)
+	}
+	if messages[0].Content != "🛍️ **Cheaper prices found**" {
+		t.Errorf("unexpected content: %s", messages[0].Content)
+	}
+
+	embed := messages[0].Embeds[0]
+	if embed.Title != "Serum" || embed.Url != "https://test.com/1" || len(embed.Fields) != 2 {
+		t.Fatalf("unexpected embed: %+v", embed)
+	}
+	if embed.Fields[0].Name != "Test Retailer" || embed.Fields[0].Value != "**£7.00** (-£3.00 | 30.00% off)\n[View listing](https://test.com/1)" {
+		t.Errorf("unexpected field: %+v", embed.Fields[0])
+	}
+	if embed.Fields[1].Name != "🆕 Other Retailer" {
+		t.Errorf("unexpected field: %+v", embed.Fields[1])
+	}
+}
+
+func TestDiscordMessagesSplit(t *testing.T) {
+	var deals []Deal
+	for i := 0; i < 12; i++ {
+		deals = append(deals, Deal{
+			Product:   fmt.Sprintf("Product %d", i),
+			BasePrice: 10.00,
+			Prices:    []DealPrice{{Listing: "Test Retailer", Url: "https://test.com", Price: 7.00}},
+		})
+	}
+	deals[0].Product = strings.Repeat("a", 300)
+
+	messages := newDiscordMessages(Notification{Kind: NotificationDeals, Title: "Deals", Categories: []DealCategory{{Name: "Other", Deals: deals}}})
+	if len(messages) != 2 || len(messages[0].Embeds) != discordMaxEmbeds || len(messages[1].Embeds) != 2 {
+		t.Fatalf("unexpected messages: %+v", messages)
+	}
+	if title := []rune(messages[0].Embeds[0].Title); len(title) != discordMaxTitle || title[len(title)-1] != '…' {
+		t.Errorf("expected a truncated title, got %s", string(title))
+	}
+
+	var items []Item
+	for i := 0; i < 100; i++ {
+		items = append(items, Item{Name: fmt.Sprintf("Product %d", i), Detail: strings.Repeat("x", 50)})
+	}
+	section := Section{Title: "Failing listings", Items: items}
+
+	messages = newDiscordMessages(Notification{Kind: NotificationListingAlerts, Title: "Alerts", Sections: []Section{section, section}})
+	if len(messages) != 2 || len(messages[0].Embeds) != 1 || len(messages[1].Embeds) != 1 {
+		t.Fatalf("expected a message per section, got %+v", messages)
+	}
+	if length := len([]rune(messages[0].Embeds[0].Description)); length != discordMaxDescription {
+		t.Errorf("expected a truncated description, got %d characters", length)
+	}
+}
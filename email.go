@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type EmailSecurity string
+
+const (
+	EmailSecurityStartTLS EmailSecurity = "starttls"
+	EmailSecurityTLS      EmailSecurity = "tls"
+	EmailSecurityNone     EmailSecurity = "none"
+)
+
+// EmailClient sends each notification as a multipart text and HTML email over SMTP
+type EmailClient struct {
+	host     string
+	port     int
+	security EmailSecurity
+	username string
+	password string
+	from     string
+	to       []string
+	// Used for the plain text part
+	templates *Templates
+}
+
+func NewEmailClient(config Email, templates *Templates) (*EmailClient, error) {
+	if config.Host == "" {
+		return nil, fmt.Errorf("email host is required")
+	}
+	if config.From == "" || len(config.To) == 0 {
+		return nil, fmt.Errorf("email from and to addresses are required")
+	}
+
+	security := EmailSecurity(strings.ToLower(config.Security))
+	port := config.Port
+	switch security {
+	case "", EmailSecurityStartTLS:
+		security = EmailSecurityStartTLS
+		if port == 0 {
+			port = 587
+		}
+	case EmailSecurityTLS:
+		if port == 0 {
+			port = 465
+		}
+	case EmailSecurityNone:
+		if port == 0 {
+			port = 25
+		}
+	default:
+		return nil, fmt.Errorf("unknown email security %q, expected one of starttls, tls or none", config.Security)
+	}
+
+	return &EmailClient{
+		host:      config.Host,
+		port:      port,
+		security:  security,
+		username:  config.Username,
+		password:  config.Password,
+		from:      config.From,
+		to:        config.To,
+		templates: templates,
+	}, nil
+}
+
+func (e *EmailClient) Notify(ctx context.Context, notification Notification) error {
+	message, err := e.buildMessage(notification)
+	if err != nil {
+		return fmt.Errorf("error building email: %v", err)
+	}
+
+	client, err := e.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if e.username != "" {
+		err = client.Auth(smtp.PlainAuth("", e.username, e.password, e.host))
+		if err != nil {
+			return fmt.Errorf("error authenticating: %v", err)
+		}
+	}
+
+	err = client.Mail(e.from)
+	if err != nil {
+		return err
+	}
+	for _, to := range e.to {
+		err = client.Rcpt(to)
+		if err != nil {
+			return fmt.Errorf("error adding recipient %s: %v", to, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(message)
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+func (e *EmailClient) Stop() error {
+	return nil
+}
+
+func (e *EmailClient) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(e.host, strconv.Itoa(e.port))
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	tlsConfig := &tls.Config{ServerName: e.host}
+
+	var conn net.Conn
+	var err error
+	if e.security == EmailSecurityTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %v", addr, err)
+	}
+
+	client, err := smtp.NewClient(conn, e.host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if e.security == EmailSecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, fmt.Errorf("%s does not support STARTTLS", addr)
+		}
+
+		err = client.StartTLS(tlsConfig)
+		if err != nil {
+			client.Close()
+			return nil, fmt.Errorf("error starting TLS: %v", err)
+		}
+	}
+
+	return client, nil
+}
+
+func (e *EmailClient) buildMessage(notification Notification) ([]byte, error) {
+	html, err := RenderHTML(notification)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := e.templates.RenderMarkdown(notification)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", html},
+	} {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := quotedprintable.NewWriter(partWriter)
+		_, err = encoder.Write([]byte(part.content))
+		if err != nil {
+			return nil, err
+		}
+		err = encoder.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", e.from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(e.to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", notification.Icon+" "+notification.Title))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", writer.Boundary())
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
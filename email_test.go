@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+type fakeSMTPServer struct {
+	listener   net.Listener
+	recipients []string
+	messages   chan string
+}
+
+// newFakeSMTPServer accepts a single connection, recording the recipients and message it's sent
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	server := &fakeSMTPServer{listener: listener, messages: make(chan string, 1)}
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) {
+			fmt.Fprintf(conn, "%s\r\n", line)
+		}
+
+		reply("220 localhost ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+
+			switch {
+			case strings.HasPrefix(command, "EHLO"):
+				reply("250-localhost")
+				reply("250 8BITMIME")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				server.recipients = append(server.recipients, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 Go ahead")
+				var message strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if dataLine == ".\r\n" {
+						break
+					}
+					message.WriteString(dataLine)
+				}
+				server.messages <- message.String()
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	return server
+}
+
+func TestEmailClient(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	defer server.listener.Close()
+
+	addr := server.listener.Addr().(*net.TCPAddr)
+	client, err := NewEmailClient(Email{
+		Host:     "127.0.0.1",
+		Port:     addr.Port,
+		Security: "none",
+		From:     "prices@test.com",
+		To:       []string{"a@test.com", "b@test.com"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	retailer := &Retailer{Name: "Test Retailer"}
+	prices := map[*Product][]SuccessScrape{
+		&Product{Name: "Serum", BasePrice: 10.00, Category: "skincare"}: {
+			{Retailer: retailer, Price: 7.00, Url: "https://test.com/1", CachedPrice: floatPtr(8.00)},
+		},
+	}
+
+	err = notify(context.Background(), prices, client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if strings.Join(server.recipients, ",") != "a@test.com,b@test.com" {
+		t.Errorf("unexpected recipients: %v", server.recipients)
+	}
+
+	message, err := mail.ReadMessage(strings.NewReader(<-server.messages))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	subject, _ := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+	if subject != "🛍️ Cheaper prices found" {
+		t.Errorf("unexpected subject: %s", subject)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected content type: %s", message.Header.Get("Content-Type"))
+	}
+
+	parts := make(map[string]string)
+	reader := multipart.NewReader(message.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		content, _ := io.ReadAll(part)
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[contentType] = string(content)
+	}
+
+	if !strings.Contains(parts["text/plain"], "Best price: **£7.00** at [Test Retailer](https://test.com/1)") {
+		t.Errorf("unexpected text part: %s", parts["text/plain"])
+	}
+	if !strings.Contains(parts["text/html"], `<td><a href="https://test.com/1">Test Retailer</a></td>`) || !strings.Contains(parts["text/html"], "<h3>skincare</h3>") {
+		t.Errorf("unexpected html part: %s", parts["text/html"])
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+type FailingListing struct {
+	Failure FailedScrape
+	Count   int
+}
+
+type RecoveredListing struct {
+	Product  *Product
+	Retailer *Retailer
+	Label    string
+	Url      string
+	Failures int
+}
+
+type ListingAlerts struct {
+	Failing   []FailingListing
+	Recovered []RecoveredListing
+}
+
+func (l ListingAlerts) Empty() bool {
+	return len(l.Failing) == 0 && len(l.Recovered) == 0
+}
+
+// FailureUpdate is a run's failures and successes, stored with Cache.SetFailures once any alerts have been sent
+type FailureUpdate struct {
+	Failures  []FailedScrape
+	Succeeded []CacheKey
+}
+
+// TrackFailures returns the listings that reach the consecutive failure threshold with this run's failures or have
+// recovered after reaching it, along with the update to store once they've been notified
+func TrackFailures(cache *Cache, prices map[*Product][]SuccessScrape, failures []FailedScrape, threshold int) (ListingAlerts, FailureUpdate, error) {
+	var alerts ListingAlerts
+	update := FailureUpdate{Failures: failures}
+
+	counts, err := cache.GetFailureCounts()
+	if err != nil {
+		return alerts, update, err
+	}
+
+	for _, failure := range failures {
+		count := counts[failure.Key()] + 1
+		if threshold > 0 && count == threshold {
+			alerts.Failing = append(alerts.Failing, FailingListing{Failure: failure, Count: count})
+		}
+	}
+
+	for product, scrapes := range prices {
+		for _, scrape := range scrapes {
+			key := scrape.Key(product)
+			update.Succeeded = append(update.Succeeded, key)
+
+			if count := counts[key]; threshold > 0 && count >= threshold {
+				alerts.Recovered = append(alerts.Recovered, RecoveredListing{Product: product, Retailer: scrape.Retailer, Label: scrape.Label, Url: scrape.Url, Failures: count})
+			}
+		}
+	}
+
+	return alerts, update, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func newTestCache(t *testing.T) *Cache {
+	cache, err := NewCache(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return cache
+}
+
+func TestTrackFailures(t *testing.T) {
+	cache := newTestCache(t)
+	product := &Product{Name: "Test Product"}
+	retailer := &Retailer{Name: "Test Retailer"}
+	failures := []FailedScrape{{Product: product, Retailer: retailer, Url: "https://test.com/1", Error: &HTTPError{StatusCode: 404, Err: errors.New("Not Found")}}}
+	prices := map[*Product][]SuccessScrape{product: {{Retailer: retailer, Price: 10.00, Url: "https://test.com/1"}}}
+
+	track := func(prices map[*Product][]SuccessScrape, failures []FailedScrape) ListingAlerts {
+		alerts, update, err := TrackFailures(cache, prices, failures, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		err = cache.SetFailures(update)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return alerts
+	}
+
+	for run := 1; run <= 3; run++ {
+		alerts := track(nil, failures)
+
+		// Only the run reaching the threshold should alert
+		expected := 0
+		if run == 2 {
+			expected = 1
+		}
+		if len(alerts.Failing) != expected {
+			t.Errorf("run %d: unexpected failing alerts: expected %d, got %d", run, expected, len(alerts.Failing))
+		}
+	}
+
+	alerts := track(prices, nil)
+	if len(alerts.Recovered) != 1 || alerts.Recovered[0].Failures != 3 {
+		t.Errorf("unexpected recovered alerts: %+v", alerts.Recovered)
+	}
+
+	alerts = track(prices, nil)
+	if !alerts.Empty() {
+		t.Errorf("unexpected alerts after recovery: %+v", alerts)
+	}
+}
+
+func TestTrackFailuresUnsaved(t *testing.T) {
+	cache := newTestCache(t)
+	product := &Product{Name: "Test Product"}
+	retailer := &Retailer{Name: "Test Retailer"}
+	failures := []FailedScrape{{Product: product, Retailer: retailer, Url: "https://test.com/1", Error: &HTTPError{StatusCode: 404, Err: errors.New("Not Found")}}}
+
+	_, update, err := TrackFailures(cache, nil, failures, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = cache.SetFailures(update)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// An alert that wasn't stored, e.g. as notifying it failed, is found again on the next run
+	for run := 1; run <= 2; run++ {
+		alerts, _, err := TrackFailures(cache, nil, failures, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(alerts.Failing) != 1 {
+			t.Errorf("run %d: unexpected failing alerts: expected 1, got %d", run, len(alerts.Failing))
+		}
+	}
+}
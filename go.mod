@@ -3,6 +3,7 @@ module product-price-scraper
 go 1.24
 
 require (
+	github.com/expr-lang/expr v1.17.8
 	github.com/gocolly/colly/v2 v2.2.0
 	github.com/mattn/go-sqlite3 v1.14.28
 	github.com/pelletier/go-toml v1.9.5
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Gotify's apps only show a notification on the phone from priority 4, and pop it up from 8
+var gotifyPriorities = map[PushPriority]int{
+	PushPriorityLow:     2,
+	PushPriorityDefault: 5,
+	PushPriorityHigh:    7,
+	PushPriorityUrgent:  9,
+}
+
+type gotifyMessage struct {
+	Title    string                 `json:"title"`
+	Message  string                 `json:"message"`
+	Priority int                    `json:"priority"`
+	Extras   map[string]interface{} `json:"extras,omitempty"`
+}
+
+// GotifyClient sends notifications to a Gotify server as an application, sending a push for each deal
+type GotifyClient struct {
+	client *http.Client
+	url    string
+	token  string
+	emoji  map[string]string
+}
+
+func NewGotifyClient(config Push) (*GotifyClient, error) {
+	if config.Server == "" {
+		return nil, fmt.Errorf("gotify server is required")
+	}
+	if config.Token == "" {
+		return nil, fmt.Errorf("gotify application token is required")
+	}
+
+	return &GotifyClient{
+		client: &http.Client{Timeout: 30 * time.Second},
+		url:    strings.TrimSuffix(config.Server, "/") + "/message",
+		token:  config.Token,
+		emoji:  config.Emoji,
+	}, nil
+}
+
+func (g *GotifyClient) Notify(ctx context.Context, notification Notification) error {
+	headers := map[string]string{"X-Gotify-Key": g.token}
+
+	for _, push := range newPushMessages(notification, g.emoji) {
+		message := gotifyMessage{
+			Title:    push.Title,
+			Message:  push.Message,
+			Priority: gotifyPriorities[push.Priority],
+			Extras:   map[string]interface{}{},
+		}
+		if push.Markdown {
+			message.Extras["client::display"] = map[string]string{"contentType": "text/markdown"}
+		}
+		if push.Url != "" {
+			message.Extras["client::notification"] = map[string]interface{}{"click": map[string]string{"url": push.Url}}
+		}
+
+		body, err := json.Marshal(message)
+		if err != nil {
+			return fmt.Errorf("error encoding message: %v", err)
+		}
+
+		err = postJSON(ctx, g.client, g.url, body, headers)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *GotifyClient) Stop() error {
+	return nil
+}
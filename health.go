@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+type HealthIssue string
+
+const (
+	HealthOk           HealthIssue = ""
+	HealthRedirected   HealthIssue = "redirected"
+	HealthDiscontinued HealthIssue = "discontinued"
+	HealthTitleChanged HealthIssue = "title_changed"
+)
+
+type ListingHealth struct {
+	Product  *Product
+	Retailer *Retailer
+	Label    string
+	Url      string
+	Issue    HealthIssue
+	Detail   string
+}
+
+func (l ListingHealth) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("product", l.Product.Name),
+		slog.String("retailer", listingName(l.Retailer, l.Label)),
+		slog.String("issue", string(l.Issue)),
+		slog.String("detail", l.Detail),
+	)
+}
+
+func (l ListingHealth) Key() CacheKey {
+	return CacheKey{Retailer: l.Retailer.Name, Product: l.Product.Name, Label: l.Label}
+}
+
+type ListingHealths []ListingHealth
+
+func (ls ListingHealths) LogValue() slog.Value {
+	attrs := make([]slog.Value, len(ls))
+	for i, l := range ls {
+		attrs[i] = l.LogValue()
+	}
+	return slog.AnyValue(attrs)
+}
+
+type PageRecord struct {
+	FirstTitle string
+	Issue      HealthIssue
+}
+
+type PageUpdate struct {
+	FirstTitle string
+	FinalUrl   string
+	Title      string
+	Issue      HealthIssue
+}
+
+// CheckListingHealth looks for listings that now redirect elsewhere, have been removed or show a different product,
+// returning all current issues and those that are new since the last run, along with the page records to store with
+// Cache.SetPageRecords once the new issues have been notified
+func CheckListingHealth(cache *Cache, prices map[*Product][]SuccessScrape, failures []FailedScrape) (issues []ListingHealth, newIssues []ListingHealth, updates map[CacheKey]PageUpdate, err error) {
+	records, err := cache.GetPageRecords()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var checked []ListingHealth
+	updates = make(map[CacheKey]PageUpdate)
+
+	for product, scrapes := range prices {
+		for _, scrape := range scrapes {
+			key := scrape.Key(product)
+			health := ListingHealth{Product: product, Retailer: scrape.Retailer, Label: scrape.Label, Url: scrape.Url}
+
+			firstTitle := scrape.Title
+			if record, ok := records[key]; ok && record.FirstTitle != "" {
+				firstTitle = record.FirstTitle
+			}
+
+			switch {
+			case !samePage(scrape.Url, scrape.FinalUrl):
+				health.Issue = HealthRedirected
+				health.Detail = fmt.Sprintf("redirected to %s", scrape.FinalUrl)
+			// A page without a title tells us nothing about whether the product changed
+			case scrape.Title != "" && firstTitle != "" && normaliseTitle(scrape.Title) != normaliseTitle(firstTitle):
+				health.Issue = HealthTitleChanged
+				health.Detail = fmt.Sprintf("page title changed from %q to %q", firstTitle, scrape.Title)
+			}
+
+			checked = append(checked, health)
+			updates[key] = PageUpdate{FirstTitle: firstTitle, FinalUrl: scrape.FinalUrl, Title: scrape.Title, Issue: health.Issue}
+		}
+	}
+
+	for _, failure := range failures {
+		if ClassifyError(failure.Error) != ErrorClassNotFound {
+			continue
+		}
+
+		detail := "page no longer exists"
+		var httpErr *HTTPError
+		if errors.As(failure.Error, &httpErr) {
+			detail = fmt.Sprintf("page no longer exists (status %d)", httpErr.StatusCode)
+		}
+
+		key := failure.Key()
+		health := ListingHealth{Product: failure.Product, Retailer: failure.Retailer, Label: failure.Label, Url: failure.Url, Issue: HealthDiscontinued, Detail: detail}
+
+		checked = append(checked, health)
+		updates[key] = PageUpdate{FirstTitle: records[key].FirstTitle, Issue: health.Issue}
+	}
+
+	for _, health := range checked {
+		if health.Issue == HealthOk {
+			continue
+		}
+
+		issues = append(issues, health)
+		if records[health.Key()].Issue != health.Issue {
+			newIssues = append(newIssues, health)
+		}
+	}
+
+	sort.Slice(newIssues, func(i, j int) bool {
+		return newIssues[i].Product.Name < newIssues[j].Product.Name
+	})
+
+	return issues, newIssues, updates, nil
+}
+
+// samePage reports whether the final URL is still the configured page, ignoring the query and trivial differences
+func samePage(configured, final string) bool {
+	if final == "" {
+		return true
+	}
+
+	configuredUrl, err := url.Parse(configured)
+	if err != nil {
+		return true
+	}
+	finalUrl, err := url.Parse(final)
+	if err != nil {
+		return true
+	}
+
+	normaliseHost := func(host string) string {
+		return strings.TrimPrefix(strings.ToLower(host), "www.")
+	}
+	normalisePath := func(path string) string {
+		return strings.TrimSuffix(strings.ToLower(path), "/")
+	}
+
+	return normaliseHost(configuredUrl.Host) == normaliseHost(finalUrl.Host) && normalisePath(configuredUrl.Path) == normalisePath(finalUrl.Path)
+}
+
+func normaliseTitle(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), " "))
+}
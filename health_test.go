@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckListingHealth(t *testing.T) {
+	cache := newTestCache(t)
+	product := &Product{Name: "Test Product"}
+	retailer := &Retailer{Name: "Test Retailer"}
+	scrape := func(finalUrl, title string) map[*Product][]SuccessScrape {
+		return map[*Product][]SuccessScrape{product: {{Retailer: retailer, Price: 10.00, Url: "https://test.com/1", FinalUrl: finalUrl, Title: title}}}
+	}
+
+	tests := []struct {
+		name     string
+		prices   map[*Product][]SuccessScrape
+		failures []FailedScrape
+		issue    HealthIssue
+		newIssue bool
+	}{
+		{name: "first scrape", prices: scrape("https://test.com/1", "Test Product 50ml"), issue: HealthOk},
+		{name: "same title", prices: scrape("https://www.test.com/1/?ref=abc", "test product  50ml"), issue: HealthOk},
+		{name: "missing title", prices: scrape("https://test.com/1", ""), issue: HealthOk},
+		{name: "title changed", prices: scrape("https://test.com/1", "Another Product"), issue: HealthTitleChanged, newIssue: true},
+		{name: "title still changed", prices: scrape("https://test.com/1", "Another Product"), issue: HealthTitleChanged},
+		{name: "redirected", prices: scrape("https://test.com/category", "Test Product 50ml"), issue: HealthRedirected, newIssue: true},
+		{
+			name:     "discontinued",
+			failures: []FailedScrape{{Product: product, Retailer: retailer, Url: "https://test.com/1", Error: &HTTPError{StatusCode: 404, Err: errors.New("Not Found")}}},
+			issue:    HealthDiscontinued,
+			newIssue: true,
+		},
+		{name: "recovered", prices: scrape("https://test.com/1", "Test Product 50ml"), issue: HealthOk},
+	}
+
+	// Each case is a run against the records left by the previous ones
+	for _, tt := range tests {
+		issues, newIssues, updates, err := CheckListingHealth(cache, tt.prices, tt.failures)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+
+		err = cache.SetPageRecords(updates)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+
+		if tt.issue == HealthOk {
+			if len(issues) != 0 {
+				t.Errorf("%s: expected no issues, got %v", tt.name, issues)
+			}
+		} else if len(issues) != 1 || issues[0].Issue != tt.issue {
+			t.Errorf("%s: expected a %s issue, got %v", tt.name, tt.issue, issues)
+		}
+
+		if tt.newIssue != (len(newIssues) == 1) {
+			t.Errorf("%s: expected new issue %t, got %v", tt.name, tt.newIssue, newIssues)
+		}
+	}
+}
+
+func TestCheckListingHealthUnsaved(t *testing.T) {
+	cache := newTestCache(t)
+	product := &Product{Name: "Test Product"}
+	prices := map[*Product][]SuccessScrape{product: {{Retailer: &Retailer{Name: "Test Retailer"}, Price: 10.00, Url: "https://test.com/1", FinalUrl: "https://test.com/category"}}}
+
+	// An issue that wasn't stored, e.g. as notifying it failed, is new again on the next run
+	for run := 1; run <= 2; run++ {
+		_, newIssues, _, err := CheckListingHealth(cache, prices, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(newIssues) != 1 || newIssues[0].Issue != HealthRedirected {
+			t.Errorf("run %d: expected a new redirected issue, got %v", run, newIssues)
+		}
+	}
+}
+
+func TestSamePage(t *testing.T) {
+	tests := []struct {
+		configured string
+		final      string
+		expected   bool
+	}{
+		{configured: "https://test.com/1", final: "", expected: true},
+		{configured: "https://test.com/1", final: "https://test.com/1", expected: true},
+		{configured: "https://www.test.com/1/", final: "https://TEST.com/1?ref=abc", expected: true},
+		{configured: "https://test.com/1", final: "https://test.com/2", expected: false},
+		{configured: "https://test.com/1", final: "https://other.com/1", expected: false},
+	}
+
+	for _, tt := range tests {
+		if actual := samePage(tt.configured, tt.final); actual != tt.expected {
+			t.Errorf("samePage(%q, %q): expected %t, got %t", tt.configured, tt.final, tt.expected, actual)
+		}
+	}
+}
+
+func TestNormaliseTitle(t *testing.T) {
+	tests := []struct {
+		title    string
+		expected string
+	}{
+		{title: "", expected: ""},
+		{title: "Test Product", expected: "test product"},
+		{title: "  Test\n\tProduct  50ml ", expected: "test product 50ml"},
+	}
+
+	for _, tt := range tests {
+		if actual := normaliseTitle(tt.title); actual != tt.expected {
+			t.Errorf("normaliseTitle(%q): expected %q, got %q", tt.title, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+var htmlTemplate = template.Must(template.New("notification").Funcs(template.FuncMap{
+	"price": func(value interface{}) string {
+		switch price := value.(type) {
+		case float64:
+			return fmt.Sprintf("£%.2f", price)
+		case *float64:
+			return fmt.Sprintf("£%.2f", *price)
+		default:
+			return ""
+		}
+	},
+	"marker":    priceChangeMarker,
+	"separator": Section.detailSeparator,
+}).Parse(`<html>
+<body style="font-family: sans-serif">
+<h2>{{.Icon}} {{.Title}}</h2>
+{{range .Categories}}
+<h3>{{.Name}}</h3>
+<table cellpadding="6" style="border-collapse: collapse">
+<tr style="text-align: left"><th>Product</th><th>Base price</th><th>Retailer</th><th>Price</th><th>Discount</th></tr>
+{{range .Deals}}{{$deal := .}}{{range $i, $price := .Prices}}
+<tr style="border-top: 1px solid #ddd">
+<td>{{if eq $i 0}}<strong>{{$deal.Product}}</strong>{{end}}</td>
+<td>{{if eq $i 0}}{{price $deal.BasePrice}}{{end}}</td>
+<td><a href="{{$price.Url}}">{{$price.Listing}}</a></td>
+<td>{{marker $price.Change}}{{if eq $i 0}}<strong>{{price $price.Price}}</strong>{{else}}{{price $price.Price}}{{end}}
+{{- if $price.StandardPrice}} (member price, {{price $price.StandardPrice}} standard){{end}}
+{{- if $price.UnitPrice}} ({{price $price.UnitPrice}}/{{$price.UnitQuantity}}){{end}}
+{{- if $price.EffectivePrice}} ({{price $price.EffectivePrice}} {{if $price.ClickAndCollect}}with click &amp; collect{{else}}delivered{{end}}){{end}}</td>
+<td>-{{price $price.Discount}}{{with $price.DiscountSize}} per {{.}}{{end}} ({{printf "%.2f" $price.DiscountPct}}% off)</td>
+</tr>
+{{end}}{{end}}
+</table>
+{{end}}
+{{range .Sections}}{{$section := .}}
+{{if .Title}}<h3>{{if .Icon}}{{.Icon}} {{end}}{{.Title}}</h3>{{end}}
+<ul>
+{{range .Items}}<li><strong>{{.Name}}</strong>{{if .Listing}} at <a href="{{.Url}}">{{.Listing}}</a>{{end}}{{if .Detail}}{{separator $section}}{{.Detail}}{{end}}</li>
+{{end}}</ul>
+{{if .Footer}}<p>{{.Footer}}</p>{{end}}
+{{end}}
+{{if .Summary}}<p><strong>{{.Summary}}</strong></p>{{end}}
+</body>
+</html>
+`))
+
+// RenderHTML renders a notification as an HTML page, with deals in a table for each category
+func RenderHTML(notification Notification) (string, error) {
+	var output strings.Builder
+	err := htmlTemplate.Execute(&output, notification)
+	return output.String(), err
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderHTMLSections(t *testing.T) {
+	notification := Notification{
+		Kind:  NotificationOutages,
+		Icon:  "🚨",
+		Title: "Retailer outages",
+		Sections: []Section{
+			{Title: "Retailers down", Items: []Item{{Name: "Boots", Detail: "blocked"}}},
+			{Title: "Retailers recovered", Sentences: true, Items: []Item{{Name: "Superdrug", Detail: "is working again"}}},
+		},
+	}
+
+	html, err := RenderHTML(notification)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{"<strong>Boots</strong>: blocked", "<strong>Superdrug</strong> is working again"} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("expected %q in %s", expected, html)
+		}
+	}
+}
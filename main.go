@@ -42,16 +42,66 @@ func main() {
 		return
 	}
 
+	retailers, err := GetRetailers(config)
+	if err != nil {
+		LogFatal(ctx, logger, "Failed to load retailers", err)
+		return
+	}
+
+	products, err := GetProducts(config, retailers)
+	if err != nil {
+		LogFatal(ctx, logger, "Failed to load products", err)
+		return
+	}
+
+	err = products.ResolveShortLinks(ctx, NewLinkResolver(cache, config.General.ShortLinkHosts))
+	if err != nil {
+		LogError(logger, "Failed to resolve short links", err)
+	}
+
+	err = products.ApplyCanonicalUrls(cache)
+	if err != nil {
+		LogFatal(ctx, logger, "Failed to apply canonical URLs", err)
+		return
+	}
+
+	for _, duplicate := range products.FindDuplicateListings() {
+		logger.Warn("Listing is configured for more than one product", slog.Any("listing", duplicate))
+	}
+
+	baskets, err := GetBaskets(config, products)
+	if err != nil {
+		LogFatal(ctx, logger, "Failed to load baskets", err)
+		return
+	}
+
+	_, err = GetAlertRules(config)
+	if err != nil {
+		LogFatal(ctx, logger, "Failed to load alert rules", err)
+		return
+	}
+
+	_, err = NewTemplates(config.Templates)
+	if err != nil {
+		LogFatal(ctx, logger, "Failed to load templates", err)
+		return
+	}
+
+	if len(os.Args) > 1 {
+		err = runCommand(ctx, os.Args[1:], config, cache, products, baskets)
+		if err != nil {
+			LogFatal(ctx, logger, "Failed to run command", err)
+		}
+		return
+	}
+
 	client, err := getClient(ctx, logger, config)
 	if err != nil {
 		LogFatal(ctx, logger, "Failed to get client", err)
 		return
 	}
 
-	retailers := GetRetailers()
-	products := GetProducts(config, retailers)
-
-	err = products.FindPricesAndNotify(ctx, logger, client, cache, config.General.MinDiscount)
+	err = products.FindPricesAndNotify(ctx, logger, client, cache, config, baskets)
 	if err != nil {
 		LogError(logger, "Failed to find prices and notify", err)
 	}
@@ -64,7 +114,7 @@ loop:
 
 		select {
 		case <-time.After(interval):
-			err = products.FindPricesAndNotify(ctx, logger, client, cache, config.General.MinDiscount)
+			err = products.FindPricesAndNotify(ctx, logger, client, cache, config, baskets)
 			if err != nil {
 				LogError(logger, "Failed to find prices and notify", err)
 			}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// RenderMarkdown renders a notification as markdown, as used for the console, Matrix and the plain text part of
+// emails. A nil Templates renders deals with the default templates. Text is written as given, so notifications that
+// are shown as formatted markdown need escaping with escapeMarkdown first.
+func (t *Templates) RenderMarkdown(notification Notification) (string, error) {
+	if t == nil {
+		t = DefaultTemplates
+	}
+
+	var message strings.Builder
+
+	if notification.Kind == NotificationDeals {
+		err := t.deals.ExecuteTemplate(&message, "message", notification)
+		if err != nil {
+			return "", fmt.Errorf("error rendering deals template: %v", err)
+		}
+		return message.String(), nil
+	}
+
+	// Listing alerts and outages are written as just their sections, each with its own heading
+	if notification.Kind != NotificationListingAlerts && notification.Kind != NotificationOutages {
+		fmt.Fprintf(&message, "%s **%s**\n\n", notification.Icon, notification.Title)
+	}
+	renderSectionsMarkdown(&message, notification)
+
+	return message.String(), nil
+}
+
+// renderSectionsMarkdown renders the sections and summary of a notification other than deals
+func renderSectionsMarkdown(message *strings.Builder, notification Notification) {
+	for _, section := range notification.Sections {
+		if section.Title != "" {
+			if section.Icon != "" {
+				fmt.Fprintf(message, "%s ", section.Icon)
+			}
+			fmt.Fprintf(message, "**%s**\n\n", section.Title)
+		}
+
+		for _, item := range section.Items {
+			fmt.Fprintf(message, "- **%s**", item.Name)
+			if item.Listing != "" {
+				fmt.Fprintf(message, " at [%s](%s)", item.Listing, item.Url)
+			}
+			if item.Detail != "" {
+				fmt.Fprintf(message, "%s%s", section.detailSeparator(), item.Detail)
+			}
+			fmt.Fprintln(message)
+		}
+
+		if section.Footer != "" {
+			fmt.Fprintln(message, section.Footer)
+		}
+		fmt.Fprintln(message)
+	}
+
+	if notification.Summary != "" {
+		fmt.Fprintf(message, "**%s**\n", notification.Summary)
+	}
+}
+
+func priceChangeMarker(change PriceChange) string {
+	switch change {
+	case PriceChangeNew:
+		return "🆕 "
+	case PriceChangeUp:
+		return "🔺 "
+	}
+	return ""
+}
+
+// priceExtras describes a price's member, unit and effective prices, which follow the price in each format
+func priceExtras(price DealPrice) string {
+	var output strings.Builder
+
+	if price.StandardPrice != nil {
+		fmt.Fprintf(&output, " 💳 member price (£%.2f standard)", *price.StandardPrice)
+	}
+	if price.UnitPrice != nil {
+		fmt.Fprintf(&output, " (£%.2f/%s)", *price.UnitPrice, price.UnitQuantity)
+	}
+	if price.EffectivePrice != nil {
+		if price.ClickAndCollect {
+			fmt.Fprintf(&output, " (£%.2f with click & collect)", *price.EffectivePrice)
+		} else {
+			fmt.Fprintf(&output, " (£%.2f delivered)", *price.EffectivePrice)
+		}
+	}
+
+	return output.String()
+}
+
+// discountText describes a price's discount, with the pack size it's for when the listing is a different size
+func discountText(price DealPrice) string {
+	if price.DiscountSize != "" {
+		return fmt.Sprintf("-£%.2f per %s | %.2f%% off", price.Discount, price.DiscountSize, price.DiscountPct)
+	}
+	return fmt.Sprintf("-£%.2f | %.2f%% off", price.Discount, price.DiscountPct)
+}
+
+// markdownText escapes the characters that could start formatting, a link or HTML. Underscores inside a word, such
+// as in not_found, can't start emphasis so are left alone.
+func markdownText(text string) string {
+	runes := []rune(text)
+
+	var escaped strings.Builder
+	for i, r := range runes {
+		switch r {
+		case '_':
+			if i > 0 && i < len(runes)-1 && isWordRune(runes[i-1]) && isWordRune(runes[i+1]) {
+				break
+			}
+			escaped.WriteRune('\\')
+		case '\\', '`', '*', '[', ']', '<', '>', '~', '|':
+			escaped.WriteRune('\\')
+		}
+		escaped.WriteRune(r)
+	}
+
+	return escaped.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+var markdownUrlEscaper = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E")
+
+// escapeMarkdown returns a copy of the notification with every field that comes from the config or a retailer's page
+// escaped, so names such as "Paula's Choice 2% BHA [Travel]" can't break or add to the markdown around them
+func escapeMarkdown(notification Notification) Notification {
+	escaped := notification
+	escaped.Title = markdownText(notification.Title)
+	escaped.Summary = markdownText(notification.Summary)
+
+	escaped.Categories = make([]DealCategory, len(notification.Categories))
+	for i, category := range notification.Categories {
+		escaped.Categories[i] = DealCategory{Name: markdownText(category.Name), Deals: make([]Deal, len(category.Deals))}
+
+		for j, deal := range category.Deals {
+			deal.Product = markdownText(deal.Product)
+			deal.Category = markdownText(deal.Category)
+
+			prices := make([]DealPrice, len(deal.Prices))
+			for k, price := range deal.Prices {
+				price.Retailer = markdownText(price.Retailer)
+				price.Label = markdownText(price.Label)
+				price.Listing = markdownText(price.Listing)
+				price.Url = markdownUrlEscaper.Replace(price.Url)
+				prices[k] = price
+			}
+			deal.Prices = prices
+
+			escaped.Categories[i].Deals[j] = deal
+		}
+	}
+
+	escaped.Sections = make([]Section, len(notification.Sections))
+	for i, section := range notification.Sections {
+		section.Title = markdownText(section.Title)
+		section.Footer = markdownText(section.Footer)
+
+		items := make([]Item, len(section.Items))
+		for j, item := range section.Items {
+			item.Name = markdownText(item.Name)
+			item.Category = markdownText(item.Category)
+			item.Listing = markdownText(item.Listing)
+			item.Url = markdownUrlEscaper.Replace(item.Url)
+			item.Detail = markdownText(item.Detail)
+			items[j] = item
+		}
+		section.Items = items
+
+		escaped.Sections[i] = section
+	}
+
+	return escaped
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdownEscaping(t *testing.T) {
+	retailer := &Retailer{Name: "Boots"}
+	prices := map[*Product][]SuccessScrape{
+		&Product{Name: "Paula's Choice 2% BHA [Travel]", BasePrice: 10.00, Category: "skin_care *sale*"}: {
+			{Retailer: retailer, Label: "[Click](https://evil.com)", Price: 7.00, Url: "https://test.com/bha_(travel)", CachedPrice: floatPtr(8.00)},
+		},
+	}
+	notification := newDealsNotification(prices)
+
+	message, err := DefaultTemplates.RenderMarkdown(escapeMarkdown(notification))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "🛍️ **Cheaper prices found** 🤑\n\n" +
+		"**skin_care \\*sale\\***\n\n" +
+		"**Paula's Choice 2% BHA \\[Travel\\]**\n" +
+		"Base price: £10.00\n" +
+		"Best price: **£7.00** at [Boots (\\[Click\\](https://evil.com))](https://test.com/bha_%28travel%29) (-£3.00 | 30.00% off)\n\n"
+	if message != expected {
+		t.Errorf("expected %q, got %q", expected, message)
+	}
+
+	content, err := newMatrixContent(nil, notification)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(content.FormattedBody, "evil.com\"") || !strings.Contains(content.FormattedBody, "Paula's Choice 2% BHA [Travel]") {
+		t.Errorf("unexpected HTML: %s", content.FormattedBody)
+	}
+
+	// The console isn't formatted, so shows the text as written
+	client := &TestClient{}
+	err = notify(context.Background(), prices, client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected = "🛍️ **Cheaper prices found** 🤑\n\n" +
+		"**skin_care *sale***\n\n" +
+		"**Paula's Choice 2% BHA [Travel]**\n" +
+		"Base price: £10.00\n" +
+		"Best price: **£7.00** at [Boots ([Click](https://evil.com))](https://test.com/bha_(travel)) (-£3.00 | 30.00% off)\n\n"
+	if client.message != expected {
+		t.Errorf("expected %q, got %q", expected, client.message)
+	}
+}
+
+func TestRenderMarkdownEscapingSections(t *testing.T) {
+	notification := Notification{
+		Kind:  NotificationPromotions,
+		Icon:  "🏷️",
+		Title: "New promotions",
+		Sections: []Section{{
+			Title: "Rule `one`",
+			Items: []Item{{
+				Name:    "<img src=x onerror=alert(1)>",
+				Listing: "Boots ~50ml~",
+				Url:     "https://test.com/a b",
+				Detail:  "_3 for 2_ | not_found",
+			}},
+		}},
+		Summary: "Total: **£5**",
+	}
+
+	message, err := DefaultTemplates.RenderMarkdown(escapeMarkdown(notification))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "🏷️ **New promotions**\n\n" +
+		"**Rule \\`one\\`**\n\n" +
+		"- **\\<img src=x onerror=alert(1)\\>** at [Boots \\~50ml\\~](https://test.com/a%20b): \\_3 for 2\\_ \\| not_found\n\n" +
+		"**Total: \\*\\*£5\\*\\***\n"
+	if message != expected {
+		t.Errorf("expected %q, got %q", expected, message)
+	}
+
+	content, err := newMatrixContent(nil, notification)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(content.FormattedBody, "<img") {
+		t.Errorf("expected the HTML to be escaped, got %s", content.FormattedBody)
+	}
+
+	if push := newPushMessages(notification, nil); !strings.Contains(push[0].Message, "\\<img") {
+		t.Errorf("expected push messages to be escaped, got %q", push[0].Message)
+	}
+
+	// The console and plain text emails show the text as written
+	message, err = DefaultTemplates.RenderMarkdown(notification)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(message, "- **<img src=x onerror=alert(1)>** at [Boots ~50ml~](https://test.com/a b): _3 for 2_ | not_found") {
+		t.Errorf("expected the text to be unescaped, got %q", message)
+	}
+}
+
+func TestDiscordEscaping(t *testing.T) {
+	messages := newDiscordMessages(Notification{
+		Kind:  NotificationDeals,
+		Title: "Cheaper prices found",
+		Categories: []DealCategory{{
+			Name: "skincare",
+			Deals: []Deal{{
+				Product:   "Paula's Choice 2% BHA [Travel]",
+				BasePrice: 10.00,
+				Prices:    []DealPrice{{Listing: "**Boots**", Url: "https://test.com/(1)", Price: 7.00, Discount: 3.00, DiscountPct: 30.00}},
+			}},
+		}},
+	})
+
+	embed := messages[0].Embeds[0]
+	if embed.Title != "Paula's Choice 2% BHA \\[Travel\\]" || embed.Url != "https://test.com/%281%29" {
+		t.Errorf("unexpected embed: %+v", embed)
+	}
+	if field := embed.Fields[0]; field.Name != "\\*\\*Boots\\*\\*" || !strings.HasSuffix(field.Value, "[View listing](https://test.com/%281%29)") {
+		t.Errorf("unexpected field: %+v", field)
+	}
+}
@@ -18,9 +18,10 @@ type MatrixClient struct {
 	client     *mautrix.Client
 	roomId     id.RoomID
 	cancelSync context.CancelFunc
+	templates  *Templates
 }
 
-func connectToMatrix(ctx context.Context, logger *slog.Logger, config Matrix, dbPath string) (*MatrixClient, error) {
+func connectToMatrix(ctx context.Context, logger *slog.Logger, config Matrix, dbPath string, templates *Templates) (*MatrixClient, error) {
 	client, err := mautrix.NewClient(config.HomeServer, id.UserID(config.UserName), config.AccessToken)
 	if err != nil {
 		return nil, fmt.Errorf("Error creating client: %v\n", err)
@@ -96,7 +97,7 @@ func connectToMatrix(ctx context.Context, logger *slog.Logger, config Matrix, db
 		}
 	}()
 
-	return &MatrixClient{client: client, roomId: id.RoomID(config.RoomID), cancelSync: cancelSyncAndWait}, nil
+	return &MatrixClient{client: client, roomId: id.RoomID(config.RoomID), cancelSync: cancelSyncAndWait, templates: templates}, nil
 }
 
 func (m *MatrixClient) Stop() error {
@@ -111,9 +112,22 @@ func (m *MatrixClient) Stop() error {
 	return nil
 }
 
-func (m *MatrixClient) SendMessage(markdown string) error {
-	content := format.RenderMarkdown(markdown, true, true)
+func (m *MatrixClient) Notify(ctx context.Context, notification Notification) error {
+	content, err := newMatrixContent(m.templates, notification)
+	if err != nil {
+		return err
+	}
 
-	_, err := m.client.SendMessageEvent(context.Background(), m.roomId, event.EventMessage, content)
+	_, err = m.client.SendMessageEvent(ctx, m.roomId, event.EventMessage, content)
 	return err
 }
+
+// newMatrixContent renders a notification as formatted markdown, escaping any text that could otherwise add to it
+func newMatrixContent(templates *Templates, notification Notification) (event.MessageEventContent, error) {
+	message, err := templates.RenderMarkdown(escapeMarkdown(notification))
+	if err != nil {
+		return event.MessageEventContent{}, err
+	}
+
+	return format.RenderMarkdown(message, true, false), nil
+}
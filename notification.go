@@ -0,0 +1,99 @@
+package main
+
+type NotificationKind string
+
+const (
+	NotificationDeals         NotificationKind = "deals"
+	NotificationListingAlerts NotificationKind = "listing_alerts"
+	NotificationOutages       NotificationKind = "outages"
+	NotificationListingHealth NotificationKind = "listing_health"
+	NotificationPromotions    NotificationKind = "promotions"
+	NotificationRuleMatches   NotificationKind = "rule_matches"
+	NotificationBasket        NotificationKind = "basket"
+)
+
+// Notification is a message built from a scrape, which each client renders in its own format
+type Notification struct {
+	Kind  NotificationKind `json:"kind"`
+	Icon  string           `json:"icon"`
+	Title string           `json:"title"`
+	// Deals grouped by category, for price notifications
+	Categories []DealCategory `json:"categories,omitempty"`
+	// The contents of any other notification, such as failing and recovered listings
+	Sections []Section `json:"sections,omitempty"`
+	// A closing line, such as a basket's total
+	Summary string `json:"summary,omitempty"`
+}
+
+type DealCategory struct {
+	Name  string `json:"name"`
+	Deals []Deal `json:"deals"`
+}
+
+type Deal struct {
+	Product   string  `json:"product"`
+	Category  string  `json:"category"`
+	BasePrice float64 `json:"base_price"`
+	// The prices found, cheapest first
+	Prices []DealPrice `json:"prices"`
+}
+
+type PriceChange string
+
+const (
+	PriceChangeNew       PriceChange = "new"
+	PriceChangeUp        PriceChange = "up"
+	PriceChangeDown      PriceChange = "down"
+	PriceChangeUnchanged PriceChange = "unchanged"
+)
+
+type DealPrice struct {
+	Retailer    string      `json:"retailer"`
+	Label       string      `json:"label,omitempty"`
+	Listing     string      `json:"listing"`
+	Url         string      `json:"url"`
+	Price       float64     `json:"price"`
+	CachedPrice *float64    `json:"cached_price,omitempty"`
+	Change      PriceChange `json:"change"`
+	// The saving against the base price, for the product's own pack size
+	Discount    float64 `json:"discount"`
+	DiscountPct float64 `json:"discount_pct"`
+	// The product's pack size the discount is for, e.g. 30ml, when the listing is a different size
+	DiscountSize string `json:"discount_size,omitempty"`
+	// The price per unit quantity, e.g. per 100ml, when the listing has a size
+	UnitPrice    *float64 `json:"unit_price,omitempty"`
+	UnitQuantity string   `json:"unit_quantity,omitempty"`
+	// The price including delivery or click and collect, when either is charged
+	EffectivePrice  *float64 `json:"effective_price,omitempty"`
+	ClickAndCollect bool     `json:"click_and_collect,omitempty"`
+	// The price without a loyalty card, when Price is a member price
+	StandardPrice *float64 `json:"standard_price,omitempty"`
+}
+
+type Section struct {
+	Icon  string `json:"icon,omitempty"`
+	Title string `json:"title,omitempty"`
+	Items []Item `json:"items"`
+	// A closing line, such as an order's subtotal
+	Footer string `json:"footer,omitempty"`
+	// Whether each item's detail carries on a sentence about it (e.g. "is working again"), rather than following a colon
+	Sentences bool `json:"-"`
+}
+
+// detailSeparator is written between an item and its detail
+func (s Section) detailSeparator() string {
+	if s.Sentences {
+		return " "
+	}
+	return ": "
+}
+
+// Item is a single entry in a section, about a product, retailer or listing
+type Item struct {
+	Name     string   `json:"name"`
+	Category string   `json:"category,omitempty"`
+	Listing  string   `json:"listing,omitempty"`
+	Url      string   `json:"url,omitempty"`
+	Price    *float64 `json:"price,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// NotifierFilter limits which deals and alerts a notifier is sent
+type NotifierFilter struct {
+	Categories  []string
+	MinDiscount float64
+}
+
+// Apply removes anything the filter doesn't allow from the notification, returning false if nothing is left to send
+func (f NotifierFilter) Apply(notification Notification) (Notification, bool) {
+	if len(f.Categories) == 0 && f.MinDiscount <= 0 {
+		return notification, true
+	}
+
+	filtered := notification
+	filtered.Categories = nil
+	for _, category := range notification.Categories {
+		var deals []Deal
+		for _, deal := range category.Deals {
+			if !f.allowsCategory(deal.Category) {
+				continue
+			}
+
+			var prices []DealPrice
+			for _, price := range deal.Prices {
+				if price.DiscountPct >= f.MinDiscount*100 {
+					prices = append(prices, price)
+				}
+			}
+			if prices != nil {
+				deal.Prices = prices
+				deals = append(deals, deal)
+			}
+		}
+
+		if deals != nil {
+			filtered.Categories = append(filtered.Categories, DealCategory{Name: category.Name, Deals: deals})
+		}
+	}
+
+	filtered.Sections = nil
+	for _, section := range notification.Sections {
+		var items []Item
+		for _, item := range section.Items {
+			// Items about a retailer rather than a product, such as outages, have no category
+			if item.Category == "" || f.allowsCategory(item.Category) {
+				items = append(items, item)
+			}
+		}
+
+		if items != nil {
+			section.Items = items
+			filtered.Sections = append(filtered.Sections, section)
+		}
+	}
+
+	return filtered, filtered.Categories != nil || filtered.Sections != nil
+}
+
+func (f NotifierFilter) allowsCategory(category string) bool {
+	return len(f.Categories) == 0 || slices.Contains(f.Categories, category)
+}
+
+// FilteredClient only passes on the parts of each notification its filter allows
+type FilteredClient struct {
+	Client
+	filter NotifierFilter
+}
+
+func (f *FilteredClient) Notify(ctx context.Context, notification Notification) error {
+	filtered, ok := f.filter.Apply(notification)
+	if !ok {
+		return nil
+	}
+	return f.Client.Notify(ctx, filtered)
+}
+
+type namedClient struct {
+	name   string
+	client Client
+}
+
+// CompositeClient sends each notification to several clients at once, so a failing or slow backend doesn't hold up
+// the others. A backend's failure is logged, and only returned as an error if every backend failed.
+type CompositeClient struct {
+	logger  *slog.Logger
+	clients []namedClient
+}
+
+func (c *CompositeClient) Add(name string, client Client) {
+	c.clients = append(c.clients, namedClient{name: name, client: client})
+}
+
+func (c *CompositeClient) Notify(ctx context.Context, notification Notification) error {
+	if len(c.clients) == 0 {
+		return nil
+	}
+
+	errs := make([]error, len(c.clients))
+
+	var wg sync.WaitGroup
+	for i, named := range c.clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := named.client.Notify(ctx, notification)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", named.name, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+
+	if failed == len(c.clients) {
+		return errors.Join(errs...)
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			LogError(c.logger, "Failed to send notification", err)
+		}
+	}
+
+	return nil
+}
+
+func (c *CompositeClient) Stop() error {
+	var errs []error
+	for _, named := range c.clients {
+		err := named.client.Stop()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", named.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func newNotifier(ctx context.Context, logger *slog.Logger, config Config, notifier NotifierTOML, templates *Templates) (Client, error) {
+	switch notifier.Type {
+	case "console":
+		return &DefaultClient{templates: templates}, nil
+	case "matrix":
+		if notifier.Matrix == nil {
+			return nil, errors.New("missing [notifiers.matrix] settings")
+		}
+		return connectToMatrix(ctx, logger, *notifier.Matrix, config.General.Database, templates)
+	case "webhook":
+		if notifier.Webhook == nil {
+			return nil, errors.New("missing [notifiers.webhook] settings")
+		}
+		return NewWebhookClient(*notifier.Webhook)
+	case "email":
+		if notifier.Email == nil {
+			return nil, errors.New("missing [notifiers.email] settings")
+		}
+		return NewEmailClient(*notifier.Email, templates)
+	case "discord":
+		if notifier.Discord == nil {
+			return nil, errors.New("missing [notifiers.discord] settings")
+		}
+		return NewDiscordClient(*notifier.Discord)
+	case "slack":
+		if notifier.Slack == nil {
+			return nil, errors.New("missing [notifiers.slack] settings")
+		}
+		return NewSlackClient(*notifier.Slack)
+	case "ntfy":
+		if notifier.Ntfy == nil {
+			return nil, errors.New("missing [notifiers.ntfy] settings")
+		}
+		return NewNtfyClient(*notifier.Ntfy)
+	case "gotify":
+		if notifier.Gotify == nil {
+			return nil, errors.New("missing [notifiers.gotify] settings")
+		}
+		return NewGotifyClient(*notifier.Gotify)
+	case "telegram":
+		if notifier.Telegram == nil {
+			return nil, errors.New("missing [notifiers.telegram] settings")
+		}
+		return NewTelegramClient(*notifier.Telegram)
+	default:
+		return nil, fmt.Errorf("unknown notifier type %q", notifier.Type)
+	}
+}
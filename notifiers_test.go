@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// failingClient fails to send every notification, or only those of the given kind when set
+type failingClient struct {
+	kind NotificationKind
+	recordingClient
+}
+
+func (f *failingClient) Notify(ctx context.Context, notification Notification) error {
+	if f.kind != "" && notification.Kind != f.kind {
+		return f.recordingClient.Notify(ctx, notification)
+	}
+	return errors.New("connection refused")
+}
+
+func (f *failingClient) Stop() error {
+	return nil
+}
+
+func TestCompositeClient(t *testing.T) {
+	console := &TestClient{}
+	skincare := &TestClient{}
+	composite := &CompositeClient{logger: testLogger()}
+	composite.Add("console", console)
+	composite.Add("broken", &failingClient{})
+	composite.Add("skincare", &FilteredClient{Client: skincare, filter: NotifierFilter{Categories: []string{"skincare"}, MinDiscount: 0.2}})
+
+	retailer := &Retailer{Name: "Test Retailer"}
+	prices := map[*Product][]SuccessScrape{
+		&Product{Name: "Serum", BasePrice: 10.00, Category: "skincare"}: {
+			{Retailer: retailer, Price: 7.00, Url: "https://test.com/1", CachedPrice: floatPtr(7.00)},
+			{Retailer: retailer, Price: 9.00, Url: "https://test.com/2", CachedPrice: floatPtr(9.00)},
+		},
+		&Product{Name: "Shampoo", BasePrice: 10.00, Category: "haircare"}: {
+			{Retailer: retailer, Price: 5.00, Url: "https://test.com/3", CachedPrice: floatPtr(5.00)},
+		},
+	}
+
+	// One backend failing is logged rather than failing the notification
+	err := notify(context.Background(), prices, composite)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// The other backends are still sent the notification
+	if !strings.Contains(console.message, "Shampoo") || !strings.Contains(console.message, "https://test.com/2") {
+		t.Errorf("expected the console to be sent every deal, got %s", console.message)
+	}
+
+	// Only skincare deals of at least 20% are sent to the filtered backend
+	expected := "🛍️ **Cheaper prices found** 🤑\n\n" +
+		"**skincare**\n\n" +
+		"**Serum**\nBase price: £10.00\nBest price: **£7.00** at [Test Retailer](https://test.com/1) (-£3.00 | 30.00% off)\n\n"
+	if skincare.message != expected {
+		t.Errorf("unexpected message: expected %s\n\ngot: %s", expected, skincare.message)
+	}
+
+	// Nothing left after filtering => not sent
+	skincare.message = ""
+	delete(prices, findProduct(prices, "Serum"))
+	_ = notify(context.Background(), prices, composite)
+	if skincare.message != "" {
+		t.Errorf("expected nothing to be sent, got %s", skincare.message)
+	}
+}
+
+func TestCompositeClientAllFailed(t *testing.T) {
+	composite := &CompositeClient{logger: testLogger()}
+	composite.Add("broken", &failingClient{})
+	composite.Add("also broken", &failingClient{})
+
+	err := composite.Notify(context.Background(), Notification{Kind: NotificationOutages})
+	if err == nil || !strings.Contains(err.Error(), "broken: connection refused") || !strings.Contains(err.Error(), "also broken: connection refused") {
+		t.Errorf("expected every backend's error, got %v", err)
+	}
+}
+
+// recordingClient keeps every notification it's sent
+type recordingClient struct {
+	notifications []Notification
+}
+
+func (r *recordingClient) Notify(ctx context.Context, notification Notification) error {
+	r.notifications = append(r.notifications, notification)
+	return nil
+}
+
+func (r *recordingClient) Stop() error {
+	return nil
+}
+
+type stubScraper struct {
+	prices map[string]float64
+}
+
+func (s stubScraper) Scrape(ctx context.Context, url string) (ScrapeResult, error) {
+	price, ok := s.prices[url]
+	if !ok {
+		return ScrapeResult{}, errors.New("not found")
+	}
+	return ScrapeResult{Price: price, FinalUrl: url}, nil
+}
+
+func TestFindPricesAndNotifyFailingNotifier(t *testing.T) {
+	cache, err := NewCache(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	retailer := &Retailer{Name: "Test Retailer", Scraper: stubScraper{prices: map[string]float64{
+		"https://test.com/1": 7.00,
+		"https://test.com/2": 4.00,
+	}}}
+	products := Products{
+		{Name: "Serum", BasePrice: 10.00, Links: []*Link{{Retailer: retailer, Url: "https://test.com/1"}}},
+		{Name: "Shampoo", BasePrice: 5.00, Links: []*Link{{Retailer: retailer, Url: "https://test.com/2"}}},
+		{Name: "Toner", BasePrice: 5.00, Links: []*Link{{Retailer: retailer, Url: "https://test.com/missing"}}},
+	}
+	config := Config{General: General{FailureAlertThreshold: 1}}
+
+	working := &recordingClient{}
+	composite := &CompositeClient{logger: testLogger()}
+	composite.Add("broken", &failingClient{})
+	composite.Add("working", working)
+
+	err = products.FindPricesAndNotify(context.Background(), testLogger(), composite, cache, config, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The failing listing alert is sent first, and the broken backend doesn't stop the deals following it
+	if len(working.notifications) != 2 || working.notifications[0].Kind != NotificationListingAlerts || working.notifications[1].Kind != NotificationDeals {
+		t.Fatalf("expected the working backend to be sent every notification, got %+v", working.notifications)
+	}
+
+	// The run carries on past the failure, so the prices are still cached
+	cachedPrices, err := cache.GetScrapes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cachedPrices) != 2 {
+		t.Errorf("expected both prices to be cached, got %v", cachedPrices)
+	}
+}
+
+func TestFindPricesAndNotifySecondaryNotificationFailed(t *testing.T) {
+	cache := newTestCache(t)
+	retailer := &Retailer{Name: "Test Retailer", Scraper: stubScraper{prices: map[string]float64{"https://test.com/1": 7.00}}}
+	products := Products{
+		{Name: "Serum", BasePrice: 10.00, Links: []*Link{{Retailer: retailer, Url: "https://test.com/1"}}},
+		{Name: "Toner", BasePrice: 5.00, Links: []*Link{{Retailer: retailer, Url: "https://test.com/missing"}}},
+	}
+	config := Config{General: General{FailureAlertThreshold: 1}}
+
+	// The listing alert failing to send doesn't stop the deals or caching the prices
+	client := &failingClient{kind: NotificationListingAlerts}
+	err := products.FindPricesAndNotify(context.Background(), testLogger(), client, cache, config, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(client.notifications) != 1 || client.notifications[0].Kind != NotificationDeals {
+		t.Fatalf("expected the deals to be sent, got %+v", client.notifications)
+	}
+
+	cachedPrices, err := cache.GetScrapes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cachedPrices) != 1 {
+		t.Errorf("expected the price to be cached, got %v", cachedPrices)
+	}
+
+	// The failure count isn't stored, so the alert is sent again next run
+	counts, err := cache.GetFailureCounts()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(counts) != 0 {
+		t.Errorf("expected no failure counts to be stored, got %v", counts)
+	}
+
+	working := &recordingClient{}
+	err = products.FindPricesAndNotify(context.Background(), testLogger(), working, cache, config, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(working.notifications) == 0 || working.notifications[0].Kind != NotificationListingAlerts {
+		t.Errorf("expected the listing alert to be sent again, got %+v", working.notifications)
+	}
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func findProduct(prices map[*Product][]SuccessScrape, name string) *Product {
+	for product := range prices {
+		if product.Name == name {
+			return product
+		}
+	}
+	return nil
+}
@@ -1,24 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"sort"
-	"strings"
 )
 
-type ProductWithScrapes struct {
-	Product *Product
-	Scrapes []SuccessScrape
+func notify(ctx context.Context, prices map[*Product][]SuccessScrape, client Client) error {
+	return client.Notify(ctx, newDealsNotification(prices))
 }
 
-func notify(prices map[*Product][]SuccessScrape, client Client) error {
-	var message strings.Builder
-	message.WriteString("🛍️ **Cheaper prices found** 🤑\n\n")
-
-	groupedByCategory := make(map[string][]*ProductWithScrapes)
+func newDealsNotification(prices map[*Product][]SuccessScrape) Notification {
+	groupedByCategory := make(map[string][]Deal)
 	for product, scrapes := range prices {
 		sort.Slice(scrapes, func(i, j int) bool {
-			return scrapes[i].Price < scrapes[j].Price
+			return scrapes[i].ComparablePrice(product) < scrapes[j].ComparablePrice(product)
 		})
 
 		category := product.Category
@@ -26,10 +23,12 @@ func notify(prices map[*Product][]SuccessScrape, client Client) error {
 			category = "Other"
 		}
 
-		groupedByCategory[category] = append(groupedByCategory[category], &ProductWithScrapes{
-			Product: product,
-			Scrapes: scrapes,
-		})
+		deal := Deal{Product: product.Name, Category: product.Category, BasePrice: product.BasePrice}
+		for _, scrape := range scrapes {
+			deal.Prices = append(deal.Prices, newDealPrice(product, scrape))
+		}
+
+		groupedByCategory[category] = append(groupedByCategory[category], deal)
 	}
 
 	categories := make([]string, 0, len(groupedByCategory))
@@ -38,59 +37,106 @@ func notify(prices map[*Product][]SuccessScrape, client Client) error {
 	}
 	sort.Strings(categories)
 
+	notification := Notification{Kind: NotificationDeals, Icon: "🛍️", Title: "Cheaper prices found"}
 	for _, category := range categories {
-		fmt.Fprintf(&message, "**%s**\n\n", category)
-
-		products := groupedByCategory[category]
-		sort.Slice(products, func(i, j int) bool {
-			return products[i].Product.Name < products[j].Product.Name
+		deals := groupedByCategory[category]
+		sort.Slice(deals, func(i, j int) bool {
+			return deals[i].Product < deals[j].Product
 		})
 
-		for _, product := range products {
-			fmt.Fprintf(&message, "**%s**\n", product.Product.Name)
-			fmt.Fprintf(&message, "Base price: £%.2f\n", product.Product.BasePrice)
+		notification.Categories = append(notification.Categories, DealCategory{Name: category, Deals: deals})
+	}
 
-			cheapest := product.Scrapes[0]
-			fmt.Fprintln(&message, cheapest.GetCheapestPriceString(product.Product))
+	return notification
+}
 
-			remaining := product.Scrapes[1:]
-			if len(remaining) > 0 {
-				fmt.Fprintln(&message, "Other prices:")
-				for _, scrape := range remaining {
-					fmt.Fprintln(&message, scrape.GetOtherPriceString(product.Product))
-				}
-			}
+func newDealPrice(product *Product, scrape SuccessScrape) DealPrice {
+	price := DealPrice{
+		Retailer:    scrape.Retailer.Name,
+		Label:       scrape.Label,
+		Listing:     listingName(scrape.Retailer, scrape.Label),
+		Url:         scrape.Url,
+		Price:       scrape.Price,
+		CachedPrice: scrape.CachedPrice,
+		Change:      PriceChangeUnchanged,
+		Discount:    product.BasePrice - scrape.ComparablePrice(product),
+	}
 
-			fmt.Fprintln(&message)
-		}
+	switch {
+	case scrape.CachedPrice == nil:
+		price.Change = PriceChangeNew
+	case scrape.Price > *scrape.CachedPrice:
+		price.Change = PriceChangeUp
+	case scrape.Price < *scrape.CachedPrice:
+		price.Change = PriceChangeDown
 	}
 
-	return client.SendMessage(message.String())
+	if product.BasePrice > 0 {
+		price.DiscountPct = price.Discount / product.BasePrice * 100
+	}
+
+	if comparablePrice := scrape.ComparablePrice(product); math.Abs(comparablePrice-scrape.Price) >= 0.005 {
+		price.DiscountSize = formatSize(product.Size, product.Unit)
+	}
+
+	if scrape.Size > 0 {
+		quantity, label := unitPriceQuantity(scrape.Unit)
+		unitPrice := scrape.Price / scrape.Size * quantity
+		price.UnitPrice = &unitPrice
+		price.UnitQuantity = label
+	}
+
+	if cost, collect := scrape.Retailer.FulfilmentCost(scrape.Price); cost > 0 {
+		effectivePrice := scrape.Price + cost
+		price.EffectivePrice = &effectivePrice
+		price.ClickAndCollect = collect
+	}
+
+	if scrape.PriceType == PriceTypeMember {
+		standardPrice := scrape.StandardPrice
+		price.StandardPrice = &standardPrice
+	}
+
+	return price
 }
 
-func GetNotifiablePrices(prices map[*Product][]SuccessScrape, minDiscount float64) map[*Product][]SuccessScrape {
+func GetNotifiablePrices(prices map[*Product][]SuccessScrape, minDiscount float64, useEffectivePrice bool) map[*Product][]SuccessScrape {
 	filteredPrices := make(map[*Product][]SuccessScrape)
 
 	for product, scrapes := range prices {
 		var notifiableScrapes []SuccessScrape
-		baseThreshold := product.BasePrice * (1 - minDiscount)
+		rules := product.notifyRules(minDiscount)
+		discount := *rules.MinDiscount
+		// Prices at or below the target price are notified even when the discount is smaller
+		baseThreshold := max(product.BasePrice*(1-discount), rules.TargetPrice)
 
 		for _, scrape := range scrapes {
+			if !rules.allowsRetailer(scrape.Retailer) {
+				continue
+			}
+
 			shouldNotify := false
+			// Compare the price for the product's own pack size, so differently sized listings are judged fairly
+			price := scrape.ComparablePrice(product)
+			cachedPricePtr := scrape.ComparableCachedPrice(product)
+			if useEffectivePrice {
+				price = scrape.ComparableEffectivePrice(product)
+				cachedPricePtr = scrape.ComparableCachedEffectivePrice(product)
+			}
 
-			if scrape.CachedPrice != nil {
-				cachedPrice := *scrape.CachedPrice
-				lowerThreshold := cachedPrice * (1 - minDiscount)
-				upperThreshold := cachedPrice * (1 + minDiscount)
+			if cachedPricePtr != nil {
+				cachedPrice := *cachedPricePtr
+				lowerThreshold := cachedPrice * (1 - discount)
+				upperThreshold := cachedPrice * (1 + discount)
 
-				droppedBelowBaseThreshold := scrape.Price <= baseThreshold && cachedPrice > baseThreshold
-				outsideCachedThreshold := scrape.Price <= lowerThreshold || scrape.Price >= upperThreshold
+				droppedBelowBaseThreshold := price <= baseThreshold && cachedPrice > baseThreshold
+				outsideCachedThreshold := price <= lowerThreshold || price >= upperThreshold
 
 				// Notify if the price dropped below the base threshold or if the price is a good discount and has changed significantly from the cache
-				shouldNotify = droppedBelowBaseThreshold || (outsideCachedThreshold && scrape.Price <= baseThreshold)
+				shouldNotify = droppedBelowBaseThreshold || (outsideCachedThreshold && price <= baseThreshold)
 			} else {
 				// No cache => notify if the price is a good discount
-				shouldNotify = scrape.Price <= baseThreshold
+				shouldNotify = price <= baseThreshold
 			}
 
 			if shouldNotify {
@@ -105,3 +151,197 @@ func GetNotifiablePrices(prices map[*Product][]SuccessScrape, minDiscount float6
 
 	return filteredPrices
 }
+
+func notifyListingAlerts(ctx context.Context, alerts ListingAlerts, client Client) error {
+	notification := Notification{Kind: NotificationListingAlerts, Icon: "🔔", Title: "Listing alerts"}
+
+	if len(alerts.Failing) > 0 {
+		sort.Slice(alerts.Failing, func(i, j int) bool {
+			return alerts.Failing[i].Failure.Product.Name < alerts.Failing[j].Failure.Product.Name
+		})
+
+		section := Section{Icon: "⚠️", Title: "Listings failing", Sentences: true}
+		for _, failing := range alerts.Failing {
+			failure := failing.Failure
+			section.Items = append(section.Items, Item{
+				Name:     failure.Product.Name,
+				Category: failure.Product.Category,
+				Listing:  listingName(failure.Retailer, failure.Label),
+				Url:      failure.Url,
+				Detail:   fmt.Sprintf("has failed %d times in a row (%s)", failing.Count, ClassifyError(failure.Error)),
+			})
+		}
+		notification.Sections = append(notification.Sections, section)
+	}
+
+	if len(alerts.Recovered) > 0 {
+		sort.Slice(alerts.Recovered, func(i, j int) bool {
+			return alerts.Recovered[i].Product.Name < alerts.Recovered[j].Product.Name
+		})
+
+		section := Section{Icon: "✅", Title: "Listings recovered", Sentences: true}
+		for _, recovered := range alerts.Recovered {
+			section.Items = append(section.Items, Item{
+				Name:     recovered.Product.Name,
+				Category: recovered.Product.Category,
+				Listing:  listingName(recovered.Retailer, recovered.Label),
+				Url:      recovered.Url,
+				Detail:   fmt.Sprintf("is working again after %d failures", recovered.Failures),
+			})
+		}
+		notification.Sections = append(notification.Sections, section)
+	}
+
+	return client.Notify(ctx, notification)
+}
+
+func notifyOutages(ctx context.Context, update OutageUpdate, client Client) error {
+	notification := Notification{Kind: NotificationOutages, Icon: "🚨", Title: "Retailer outages"}
+
+	if len(update.Started) > 0 {
+		section := Section{Icon: "🚨", Title: "Retailer outages"}
+		for _, outage := range update.Started {
+			section.Items = append(section.Items, Item{
+				Name:   outage.Retailer.Name,
+				Detail: fmt.Sprintf("%d of %d listings failing with %s (%s)", outage.Failed, outage.Total, outage.Class, outage.Description()),
+			})
+		}
+		notification.Sections = append(notification.Sections, section)
+	}
+
+	if len(update.Recovered) > 0 {
+		section := Section{Icon: "✅", Title: "Retailers recovered", Sentences: true}
+		for _, retailer := range update.Recovered {
+			section.Items = append(section.Items, Item{Name: retailer.Name, Detail: "is working again"})
+		}
+		notification.Sections = append(notification.Sections, section)
+	}
+
+	return client.Notify(ctx, notification)
+}
+
+func notifyListingHealth(ctx context.Context, issues []ListingHealth, client Client) error {
+	var section Section
+	for _, health := range issues {
+		section.Items = append(section.Items, Item{
+			Name:     health.Product.Name,
+			Category: health.Product.Category,
+			Listing:  listingName(health.Retailer, health.Label),
+			Url:      health.Url,
+			Detail:   health.Detail,
+		})
+	}
+
+	return client.Notify(ctx, Notification{Kind: NotificationListingHealth, Icon: "🔎", Title: "Listing health", Sections: []Section{section}})
+}
+
+func notifyPromotions(ctx context.Context, promotions []NewPromotion, client Client) error {
+	sort.Slice(promotions, func(i, j int) bool {
+		if promotions[i].Product.Name != promotions[j].Product.Name {
+			return promotions[i].Product.Name < promotions[j].Product.Name
+		}
+		return listingName(promotions[i].Scrape.Retailer, promotions[i].Scrape.Label) < listingName(promotions[j].Scrape.Retailer, promotions[j].Scrape.Label)
+	})
+
+	var section Section
+	for _, promotion := range promotions {
+		scrape := promotion.Scrape
+		detail := promotion.Promotion.Text
+		if effective := promotion.Promotion.EffectivePrice; effective != nil {
+			detail += fmt.Sprintf(" (£%.2f each instead of £%.2f%s)", *effective, scrape.Price, scrape.formatUnitPrice(*effective))
+		}
+
+		section.Items = append(section.Items, Item{
+			Name:     promotion.Product.Name,
+			Category: promotion.Product.Category,
+			Listing:  listingName(scrape.Retailer, scrape.Label),
+			Url:      scrape.Url,
+			Price:    promotion.Promotion.EffectivePrice,
+			Detail:   detail,
+		})
+	}
+
+	return client.Notify(ctx, Notification{Kind: NotificationPromotions, Icon: "🏷️", Title: "New promotions", Sections: []Section{section}})
+}
+
+func notifyRuleMatches(ctx context.Context, matches []RuleMatch, client Client) error {
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Rule.Name != matches[j].Rule.Name {
+			return matches[i].Rule.Name < matches[j].Rule.Name
+		}
+		return matches[i].Product.Name < matches[j].Product.Name
+	})
+
+	notification := Notification{Kind: NotificationRuleMatches, Icon: "📣", Title: "Alert rules matched"}
+	for _, match := range matches {
+		if len(notification.Sections) == 0 || notification.Sections[len(notification.Sections)-1].Title != match.Rule.Name {
+			notification.Sections = append(notification.Sections, Section{Title: match.Rule.Name})
+		}
+
+		scrape := match.Scrape
+		price := scrape.Price
+		section := &notification.Sections[len(notification.Sections)-1]
+		section.Items = append(section.Items, Item{
+			Name:     match.Product.Name,
+			Category: match.Product.Category,
+			Listing:  listingName(scrape.Retailer, scrape.Label),
+			Url:      scrape.Url,
+			Price:    &price,
+			Detail:   fmt.Sprintf("£%.2f", price),
+		})
+	}
+
+	return client.Notify(ctx, notification)
+}
+
+func notifyBasket(ctx context.Context, plan BasketPlan, client Client) error {
+	notification := Notification{Kind: NotificationBasket, Icon: "🧺", Title: plan.Basket.Name}
+
+	for _, order := range plan.Orders {
+		section := Section{Title: order.Retailer.Name}
+		for _, line := range order.Lines {
+			price := line.Price
+			detail := fmt.Sprintf("£%.2f", price)
+			if math.Abs(line.ComparablePrice-price) >= 0.005 {
+				detail += fmt.Sprintf(" (£%.2f per %s)", line.ComparablePrice, formatSize(line.Product.Size, line.Product.Unit))
+			}
+			section.Items = append(section.Items, Item{
+				Name:     line.Product.Name,
+				Category: line.Product.Category,
+				Listing:  listingName(line.Retailer, line.Label),
+				Url:      line.Url,
+				Price:    &price,
+				Detail:   detail,
+			})
+		}
+
+		delivery := "free delivery"
+		if order.Collect {
+			delivery = "free click & collect"
+			if order.Delivery > 0 {
+				delivery = fmt.Sprintf("£%.2f click & collect", order.Delivery)
+			}
+		} else if order.Delivery > 0 {
+			delivery = fmt.Sprintf("£%.2f delivery", order.Delivery)
+		}
+		section.Footer = fmt.Sprintf("Subtotal: £%.2f + %s", order.Subtotal, delivery)
+
+		notification.Sections = append(notification.Sections, section)
+	}
+
+	if len(plan.Unavailable) > 0 {
+		section := Section{Title: "No prices yet"}
+		for _, product := range plan.Unavailable {
+			section.Items = append(section.Items, Item{Name: product.Name, Category: product.Category})
+		}
+		notification.Sections = append(notification.Sections, section)
+	}
+
+	if !plan.Available() {
+		notification.Summary = "Unavailable: none of the products have a price yet"
+	} else {
+		notification.Summary = fmt.Sprintf("Total: £%.2f (base price £%.2f, saving £%.2f)", plan.Total, plan.BaseTotal, plan.Saving())
+	}
+
+	return client.Notify(ctx, notification)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"testing"
 )
 
@@ -8,9 +9,10 @@ type TestClient struct {
 	message string
 }
 
-func (t *TestClient) SendMessage(markdown string) error {
-	t.message = markdown
-	return nil
+func (t *TestClient) Notify(ctx context.Context, notification Notification) error {
+	message, err := DefaultTemplates.RenderMarkdown(notification)
+	t.message = message
+	return err
 }
 
 func (t *TestClient) Stop() error {
@@ -90,7 +92,7 @@ func TestNotify(t *testing.T) {
 	}
 	client := &TestClient{}
 
-	err := notify(prices, client)
+	err := notify(context.Background(), prices, client)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -110,6 +112,38 @@ func TestNotify(t *testing.T) {
 	}
 }
 
+func TestNotifyMemberPrices(t *testing.T) {
+	prices := map[*Product][]SuccessScrape{
+		&Product{
+			Name:      "Test Product",
+			BasePrice: 100.00,
+		}: {
+			{
+				Retailer:      &Retailer{Name: "Test Retailer", Member: true},
+				Price:         75.00,
+				Url:           "https://test.com/1",
+				CachedPrice:   floatPtr(95.00),
+				PriceType:     PriceTypeMember,
+				StandardPrice: 85.00,
+			},
+		},
+	}
+	client := &TestClient{}
+
+	err := notify(context.Background(), prices, client)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// A member price is shown alongside the standard price
+	expected := "🛍️ **Cheaper prices found** 🤑\n\n" +
+		"**Other**\n\n" +
+		"**Test Product**\nBase price: £100.00\nBest price: **£75.00** 💳 member price (£85.00 standard) at [Test Retailer](https://test.com/1) (-£25.00 | 25.00% off)\n\n"
+	if client.message != expected {
+		t.Errorf("unexpected message: expected %s\n\ngot: %s", expected, client.message)
+	}
+}
+
 func TestGetNotifiablePrices(t *testing.T) {
 	product := &Product{
 		Name:      "Test Product",
@@ -122,48 +156,48 @@ func TestGetNotifiablePrices(t *testing.T) {
 		product: {
 			// Prices without a cached price:
 			// Price is the same as the base price => should not be included
-			{retailer, product.BasePrice, "https://test.com/1", nil},
+			{Retailer: retailer, Price: product.BasePrice, Url: "https://test.com/1", CachedPrice: nil},
 			// Price is lower than the base price by less the min discount => should not be included
-			{retailer, product.BasePrice * 0.95, "https://test.com/2", nil},
+			{Retailer: retailer, Price: product.BasePrice * 0.95, Url: "https://test.com/2", CachedPrice: nil},
 			// Price is lower than the base price by the min discount => should be included
-			{retailer, product.BasePrice * 0.9, "https://test.com/3", nil},
+			{Retailer: retailer, Price: product.BasePrice * 0.9, Url: "https://test.com/3", CachedPrice: nil},
 			// Price is lower than the base price by more than the min discount => should be included
-			{retailer, product.BasePrice * 0.8, "https://test.com/4", nil},
+			{Retailer: retailer, Price: product.BasePrice * 0.8, Url: "https://test.com/4", CachedPrice: nil},
 
 			// Prices with a cached price:
 			// Price is the same as the cached price => should not be included
-			{retailer, product.BasePrice * 0.9, "https://test.com/5", floatPtr(product.BasePrice * 0.9)},
+			{Retailer: retailer, Price: product.BasePrice * 0.9, Url: "https://test.com/5", CachedPrice: floatPtr(product.BasePrice * 0.9)},
 			// Price is below the base threshold but higher than the lower cache threshold => should not be included
-			{retailer, product.BasePrice * 0.85, "https://test.com/6", floatPtr(product.BasePrice * 0.9)},
+			{Retailer: retailer, Price: product.BasePrice * 0.85, Url: "https://test.com/6", CachedPrice: floatPtr(product.BasePrice * 0.9)},
 			// Price falls below the base threshold but is higher than the lower cache threshold  => should be included
-			{retailer, product.BasePrice * 0.85, "https://test.com/7", floatPtr(product.BasePrice * 0.91)},
+			{Retailer: retailer, Price: product.BasePrice * 0.85, Url: "https://test.com/7", CachedPrice: floatPtr(product.BasePrice * 0.91)},
 			// Price is below the base threshold and at the lower cache threshold => should be included
-			{retailer, product.BasePrice * 0.81, "https://test.com/8", floatPtr(product.BasePrice * 0.9)},
+			{Retailer: retailer, Price: product.BasePrice * 0.81, Url: "https://test.com/8", CachedPrice: floatPtr(product.BasePrice * 0.9)},
 			// Price is below the base and cache price thresholds => should be included
-			{retailer, product.BasePrice * 0.79, "https://test.com/9", floatPtr(product.BasePrice * 0.9)},
+			{Retailer: retailer, Price: product.BasePrice * 0.79, Url: "https://test.com/9", CachedPrice: floatPtr(product.BasePrice * 0.9)},
 			// Price is below the base threshold but has increased by less than the upper cache threshold => should not be included
-			{retailer, product.BasePrice * 0.83, "https://test.com/10", floatPtr(product.BasePrice * 0.8)},
+			{Retailer: retailer, Price: product.BasePrice * 0.83, Url: "https://test.com/10", CachedPrice: floatPtr(product.BasePrice * 0.8)},
 			// Price is below the base threshold and has increased to the upper cache threshold => should be included
-			{retailer, product.BasePrice * 0.88, "https://test.com/11", floatPtr(product.BasePrice * 0.8)},
+			{Retailer: retailer, Price: product.BasePrice * 0.88, Url: "https://test.com/11", CachedPrice: floatPtr(product.BasePrice * 0.8)},
 			// Price is below the base threshold and has increased beyond the upper cache threshold => should be included
-			{retailer, product.BasePrice * 0.89, "https://test.com/12", floatPtr(product.BasePrice * 0.8)},
+			{Retailer: retailer, Price: product.BasePrice * 0.89, Url: "https://test.com/12", CachedPrice: floatPtr(product.BasePrice * 0.8)},
 			// Price is above the base threshold but is below the upper cache threshold => should not be included
-			{retailer, product.BasePrice * 0.91, "https://test.com/13", floatPtr(product.BasePrice * 0.8)},
+			{Retailer: retailer, Price: product.BasePrice * 0.91, Url: "https://test.com/13", CachedPrice: floatPtr(product.BasePrice * 0.8)},
 		},
 	}
 	expected := map[*Product][]SuccessScrape{
 		product: {
-			{retailer, product.BasePrice * 0.9, "https://test.com/3", nil},
-			{retailer, product.BasePrice * 0.8, "https://test.com/4", nil},
-			{retailer, product.BasePrice * 0.85, "https://test.com/7", floatPtr(product.BasePrice * 0.91)},
-			{retailer, product.BasePrice * 0.81, "https://test.com/8", floatPtr(product.BasePrice * 0.9)},
-			{retailer, product.BasePrice * 0.79, "https://test.com/9", floatPtr(product.BasePrice * 0.9)},
-			{retailer, product.BasePrice * 0.88, "https://test.com/11", floatPtr(product.BasePrice * 0.8)},
-			{retailer, product.BasePrice * 0.89, "https://test.com/12", floatPtr(product.BasePrice * 0.8)},
+			{Retailer: retailer, Price: product.BasePrice * 0.9, Url: "https://test.com/3", CachedPrice: nil},
+			{Retailer: retailer, Price: product.BasePrice * 0.8, Url: "https://test.com/4", CachedPrice: nil},
+			{Retailer: retailer, Price: product.BasePrice * 0.85, Url: "https://test.com/7", CachedPrice: floatPtr(product.BasePrice * 0.91)},
+			{Retailer: retailer, Price: product.BasePrice * 0.81, Url: "https://test.com/8", CachedPrice: floatPtr(product.BasePrice * 0.9)},
+			{Retailer: retailer, Price: product.BasePrice * 0.79, Url: "https://test.com/9", CachedPrice: floatPtr(product.BasePrice * 0.9)},
+			{Retailer: retailer, Price: product.BasePrice * 0.88, Url: "https://test.com/11", CachedPrice: floatPtr(product.BasePrice * 0.8)},
+			{Retailer: retailer, Price: product.BasePrice * 0.89, Url: "https://test.com/12", CachedPrice: floatPtr(product.BasePrice * 0.8)},
 		},
 	}
 
-	filteredPrices := GetNotifiablePrices(prices, 0.1)
+	filteredPrices := GetNotifiablePrices(prices, 0.1, false)
 
 	actualScrapes := filteredPrices[product]
 	expectedScrapes := expected[product]
@@ -181,3 +215,228 @@ func TestGetNotifiablePrices(t *testing.T) {
 		}
 	}
 }
+
+func TestNotifyLabelledListings(t *testing.T) {
+	retailer := &Retailer{
+		Name: "Test Retailer",
+	}
+	prices := map[*Product][]SuccessScrape{
+		&Product{
+			Name:      "Test Product",
+			BasePrice: 90.00,
+		}: {
+			{
+				Retailer:    retailer,
+				Price:       60.00,
+				Url:         "https://test.com/1",
+				CachedPrice: floatPtr(60.00),
+			},
+			{
+				Retailer:    retailer,
+				Price:       70.00,
+				Url:         "https://test.com/1/multipack",
+				CachedPrice: floatPtr(70.00),
+				Label:       "Multipack",
+			},
+		},
+	}
+	client := &TestClient{}
+
+	err := notify(context.Background(), prices, client)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Each of a retailer's listings is shown with its label
+	expected := "🛍️ **Cheaper prices found** 🤑\n\n" +
+		"**Other**\n\n" +
+		"**Test Product**\nBase price: £90.00\nBest price: **£60.00** at [Test Retailer](https://test.com/1) (-£30.00 | 33.33% off)\n" +
+		"Other prices:\n- £70.00 at [Test Retailer (Multipack)](https://test.com/1/multipack) (-£20.00 | 22.22% off)\n\n"
+	if client.message != expected {
+		t.Errorf("unexpected message: expected %s\n\ngot: %s", expected, client.message)
+	}
+}
+
+func TestNotifyUnitPrices(t *testing.T) {
+	retailer := &Retailer{
+		Name: "Test Retailer",
+	}
+	prices := map[*Product][]SuccessScrape{
+		&Product{
+			Name:      "Test Serum",
+			BasePrice: 10.00,
+			Size:      50,
+			Unit:      "ml",
+		}: {
+			{
+				Retailer:    retailer,
+				Price:       8.00,
+				Url:         "https://test.com/50ml",
+				CachedPrice: floatPtr(8.00),
+				Size:        50,
+				Unit:        "ml",
+			},
+			{
+				Retailer:    retailer,
+				Price:       15.00,
+				Url:         "https://test.com/100ml",
+				CachedPrice: floatPtr(15.00),
+				Label:       "Large",
+				Size:        100,
+				Unit:        "ml",
+			},
+		},
+	}
+	client := &TestClient{}
+
+	err := notify(context.Background(), prices, client)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// The larger pack is cheaper per ml, so should be ranked first despite its higher price, with its discount for the
+	// product's own 50ml
+	expected := "🛍️ **Cheaper prices found** 🤑\n\n" +
+		"**Other**\n\n" +
+		"**Test Serum**\nBase price: £10.00\nBest price: **£15.00** (£15.00/100ml) at [Test Retailer (Large)](https://test.com/100ml) (-£2.50 per 50ml | 25.00% off)\n" +
+		"Other prices:\n- £8.00 (£16.00/100ml) at [Test Retailer](https://test.com/50ml) (-£2.00 | 20.00% off)\n\n"
+	if client.message != expected {
+		t.Errorf("unexpected message: expected %s\n\ngot: %s", expected, client.message)
+	}
+}
+
+func TestEffectivePrices(t *testing.T) {
+	product := &Product{
+		Name:      "Test Product",
+		BasePrice: 10.00,
+	}
+	delivered := &Retailer{Name: "Delivered", DeliveryCharge: 3.99, FreeDeliveryThreshold: 25.00}
+	collected := &Retailer{Name: "Collected", DeliveryCharge: 3.99, ClickAndCollect: true, CollectionFee: 0.50}
+	prices := map[*Product][]SuccessScrape{
+		product: {
+			// A good discount, but not once delivery is added => only included without effective prices
+			{Retailer: delivered, Price: 8.00, Url: "https://test.com/1"},
+			// Click and collect is cheaper than delivery => included either way
+			{Retailer: collected, Price: 8.50, Url: "https://test.com/2"},
+		},
+	}
+
+	if filtered := GetNotifiablePrices(prices, 0.1, false); len(filtered[product]) != 2 {
+		t.Errorf("unexpected length: expected 2, got %d", len(filtered[product]))
+	}
+
+	filtered := GetNotifiablePrices(prices, 0.1, true)
+	if len(filtered[product]) != 1 || filtered[product][0].Url != "https://test.com/2" {
+		t.Fatalf("expected only the click and collect listing, got %+v", filtered[product])
+	}
+
+	client := &TestClient{}
+	err := notify(context.Background(), prices, client)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	expected := "🛍️ **Cheaper prices found** 🤑\n\n" +
+		"**Other**\n\n" +
+		"**Test Product**\nBase price: £10.00\nBest price: 🆕 **£8.00** (£11.99 delivered) at [Delivered](https://test.com/1) (-£2.00 | 20.00% off)\n" +
+		"Other prices:\n- 🆕 £8.50 (£9.00 with click & collect) at [Collected](https://test.com/2) (-£1.50 | 15.00% off)\n\n"
+	if client.message != expected {
+		t.Errorf("unexpected message: expected %s\n\ngot: %s", expected, client.message)
+	}
+}
+
+func TestGetNotifiablePricesRules(t *testing.T) {
+	boots := &Retailer{Name: "Boots"}
+	superdrug := &Retailer{Name: "Superdrug"}
+	categoryDiscount, productDiscount := 0.3, 0.05
+
+	// Category minimum discount overrides the general one
+	cleanser := &Product{Name: "Cleanser", BasePrice: 10.00, CategoryRules: NotifyRules{MinDiscount: &categoryDiscount}}
+	// Product minimum discount overrides the category's, and only Boots prices are wanted
+	serum := &Product{
+		Name:          "Serum",
+		BasePrice:     50.00,
+		Rules:         NotifyRules{MinDiscount: &productDiscount, Retailers: map[*Retailer]bool{boots: true}},
+		CategoryRules: NotifyRules{MinDiscount: &categoryDiscount},
+	}
+	// The target price is notified despite being a smaller discount than the minimum
+	moisturiser := &Product{Name: "Moisturiser", BasePrice: 20.00, Rules: NotifyRules{TargetPrice: 19.00}}
+
+	prices := map[*Product][]SuccessScrape{
+		cleanser: {
+			{Retailer: boots, Price: 8.00, Url: "https://test.com/1"},
+			{Retailer: superdrug, Price: 7.00, Url: "https://test.com/2"},
+		},
+		serum: {
+			{Retailer: boots, Price: 47.00, Url: "https://test.com/3"},
+			{Retailer: superdrug, Price: 40.00, Url: "https://test.com/4"},
+		},
+		moisturiser: {
+			{Retailer: boots, Price: 19.00, Url: "https://test.com/5"},
+			{Retailer: superdrug, Price: 19.50, Url: "https://test.com/6"},
+		},
+	}
+
+	filteredPrices := GetNotifiablePrices(prices, 0.1, false)
+
+	expected := map[*Product][]string{
+		cleanser:    {"https://test.com/2"},
+		serum:       {"https://test.com/3"},
+		moisturiser: {"https://test.com/5"},
+	}
+	for product, urls := range expected {
+		actual := filteredPrices[product]
+		if len(actual) != len(urls) {
+			t.Errorf("%s: unexpected length: expected %d, got %d", product.Name, len(urls), len(actual))
+			continue
+		}
+		for i, url := range urls {
+			if actual[i].Url != url {
+				t.Errorf("%s: unexpected url: expected %s, got %s", product.Name, url, actual[i].Url)
+			}
+		}
+	}
+}
+
+func TestNotifyListingAlerts(t *testing.T) {
+	product := &Product{Name: "Test Product"}
+	retailer := &Retailer{Name: "Test Retailer"}
+	alerts := ListingAlerts{
+		Failing: []FailingListing{{
+			Failure: FailedScrape{Product: product, Retailer: retailer, Url: "https://test.com/1", Error: &HTTPError{StatusCode: 404}},
+			Count:   3,
+		}},
+		Recovered: []RecoveredListing{{Product: product, Retailer: retailer, Label: "Twin pack", Url: "https://test.com/2", Failures: 5}},
+	}
+	client := &TestClient{}
+
+	err := notifyListingAlerts(context.Background(), alerts, client)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	expected := "⚠️ **Listings failing**\n\n- **Test Product** at [Test Retailer](https://test.com/1) has failed 3 times in a row (not_found)\n\n" +
+		"✅ **Listings recovered**\n\n- **Test Product** at [Test Retailer (Twin pack)](https://test.com/2) is working again after 5 failures\n\n"
+	if client.message != expected {
+		t.Errorf("unexpected message: expected %s\n\ngot: %s", expected, client.message)
+	}
+}
+
+func TestNotifyOutages(t *testing.T) {
+	update := OutageUpdate{
+		Started:   []RetailerOutage{{Retailer: &Retailer{Name: "Boots"}, Class: ErrorClassBlocked, Failed: 3, Total: 4}},
+		Recovered: []*Retailer{{Name: "Superdrug"}},
+	}
+	client := &TestClient{}
+
+	err := notifyOutages(context.Background(), update, client)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	expected := "🚨 **Retailer outages**\n\n- **Boots**: 3 of 4 listings failing with blocked (blocking requests)\n\n" +
+		"✅ **Retailers recovered**\n\n- **Superdrug** is working again\n\n"
+	if client.message != expected {
+		t.Errorf("unexpected message: expected %s\n\ngot: %s", expected, client.message)
+	}
+}
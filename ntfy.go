@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const defaultNtfyServer = "https://ntfy.sh"
+
+var ntfyPriorities = map[PushPriority]int{
+	PushPriorityLow:     2,
+	PushPriorityDefault: 3,
+	PushPriorityHigh:    4,
+	PushPriorityUrgent:  5,
+}
+
+type ntfyMessage struct {
+	Topic    string   `json:"topic"`
+	Title    string   `json:"title"`
+	Message  string   `json:"message"`
+	Priority int      `json:"priority"`
+	Tags     []string `json:"tags,omitempty"`
+	Click    string   `json:"click,omitempty"`
+	Markdown bool     `json:"markdown,omitempty"`
+}
+
+// NtfyClient publishes notifications to an ntfy topic, sending a push for each deal
+type NtfyClient struct {
+	client *http.Client
+	server string
+	topic  string
+	token  string
+	emoji  map[string]string
+}
+
+func NewNtfyClient(config Push) (*NtfyClient, error) {
+	if config.Topic == "" {
+		return nil, fmt.Errorf("ntfy topic is required")
+	}
+
+	server := config.Server
+	if server == "" {
+		server = defaultNtfyServer
+	}
+
+	return &NtfyClient{
+		client: &http.Client{Timeout: 30 * time.Second},
+		server: server,
+		topic:  config.Topic,
+		token:  config.Token,
+		emoji:  config.Emoji,
+	}, nil
+}
+
+func (n *NtfyClient) Notify(ctx context.Context, notification Notification) error {
+	headers := map[string]string{}
+	if n.token != "" {
+		headers["Authorization"] = "Bearer " + n.token
+	}
+
+	for _, push := range newPushMessages(notification, n.emoji) {
+		message := ntfyMessage{
+			Topic:    n.topic,
+			Title:    push.Title,
+			Message:  push.Message,
+			Priority: ntfyPriorities[push.Priority],
+			Click:    push.Url,
+			Markdown: push.Markdown,
+		}
+		if push.Category != "" {
+			message.Tags = []string{push.Category}
+		}
+
+		body, err := json.Marshal(message)
+		if err != nil {
+			return fmt.Errorf("error encoding message: %v", err)
+		}
+
+		err = postJSON(ctx, n.client, n.server, body, headers)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (n *NtfyClient) Stop() error {
+	return nil
+}
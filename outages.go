@@ -0,0 +1,188 @@
+package main
+
+import (
+	"log/slog"
+	"sort"
+)
+
+type RetailerOutage struct {
+	Retailer *Retailer
+	Class    ErrorClass
+	Failed   int
+	Total    int
+	Error    error
+}
+
+func (o RetailerOutage) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("retailer", o.Retailer.Name),
+		slog.String("class", string(o.Class)),
+		slog.Int("failed", o.Failed),
+		slog.Int("total", o.Total),
+		slog.String("err", o.Error.Error()),
+	)
+}
+
+func (o RetailerOutage) Description() string {
+	switch o.Class {
+	case ErrorClassSelector, ErrorClassParse:
+		return "selector broken"
+	case ErrorClassNotFound:
+		return "listings missing"
+	case ErrorClassBlocked:
+		return "blocking requests"
+	default:
+		return "retailer down"
+	}
+}
+
+type OutageUpdate struct {
+	Started   []RetailerOutage
+	Recovered []*Retailer
+}
+
+func (o OutageUpdate) Empty() bool {
+	return len(o.Started) == 0 && len(o.Recovered) == 0
+}
+
+func (o Outages) minListings() int {
+	if o.MinListings <= 0 {
+		return 2
+	}
+	return o.MinListings
+}
+
+func (o Outages) failureRatio() float64 {
+	if o.FailureRatio <= 0 {
+		return 0.75
+	}
+	return o.FailureRatio
+}
+
+// DetectOutages finds retailers where most listings failed this run with the same class of error
+func DetectOutages(prices map[*Product][]SuccessScrape, failures []FailedScrape, config Outages) []RetailerOutage {
+	totals := make(map[*Retailer]int)
+	for _, scrapes := range prices {
+		for _, scrape := range scrapes {
+			totals[scrape.Retailer]++
+		}
+	}
+
+	failuresByClass := make(map[*Retailer]map[ErrorClass][]FailedScrape)
+	for _, failure := range failures {
+		totals[failure.Retailer]++
+		if failuresByClass[failure.Retailer] == nil {
+			failuresByClass[failure.Retailer] = make(map[ErrorClass][]FailedScrape)
+		}
+		class := ClassifyError(failure.Error)
+		failuresByClass[failure.Retailer][class] = append(failuresByClass[failure.Retailer][class], failure)
+	}
+
+	var outages []RetailerOutage
+	for retailer, byClass := range failuresByClass {
+		total := totals[retailer]
+		if total < config.minListings() {
+			continue
+		}
+
+		var outage *RetailerOutage
+		for class, classFailures := range byClass {
+			if outage == nil || len(classFailures) > outage.Failed {
+				outage = &RetailerOutage{Retailer: retailer, Class: class, Failed: len(classFailures), Total: total, Error: classFailures[0].Error}
+			}
+		}
+
+		if float64(outage.Failed)/float64(total) >= config.failureRatio() {
+			outages = append(outages, *outage)
+		}
+	}
+
+	sort.Slice(outages, func(i, j int) bool {
+		return outages[i].Retailer.Name < outages[j].Retailer.Name
+	})
+
+	return outages
+}
+
+// UpdateOutages returns the outages that have started or ended since the last run, to store with Cache.SetOutages
+// once they've been notified
+func UpdateOutages(cache *Cache, outages []RetailerOutage, prices map[*Product][]SuccessScrape) (OutageUpdate, error) {
+	var update OutageUpdate
+
+	previous, err := cache.GetOutages()
+	if err != nil {
+		return update, err
+	}
+
+	current := make(map[string]bool)
+	for _, outage := range outages {
+		current[outage.Retailer.Name] = true
+		if class, ok := previous[outage.Retailer.Name]; !ok || class != outage.Class {
+			update.Started = append(update.Started, outage)
+		}
+	}
+
+	// Only a retailer with a successful scrape this run can be considered recovered
+	seen := make(map[string]bool)
+	for _, scrapes := range prices {
+		for _, scrape := range scrapes {
+			name := scrape.Retailer.Name
+			if _, ok := previous[name]; ok && !current[name] && !seen[name] {
+				seen[name] = true
+				update.Recovered = append(update.Recovered, scrape.Retailer)
+			}
+		}
+	}
+
+	sort.Slice(update.Recovered, func(i, j int) bool {
+		return update.Recovered[i].Name < update.Recovered[j].Name
+	})
+
+	return update, nil
+}
+
+func retailersDown(outages []RetailerOutage) map[*Retailer]bool {
+	down := make(map[*Retailer]bool)
+	for _, outage := range outages {
+		down[outage.Retailer] = true
+	}
+	return down
+}
+
+// withoutOutages removes the failures already covered by a retailer outage
+func withoutOutages(failures []FailedScrape, outages []RetailerOutage) []FailedScrape {
+	down := retailersDown(outages)
+
+	var remaining []FailedScrape
+	for _, failure := range failures {
+		if !down[failure.Retailer] {
+			remaining = append(remaining, failure)
+		}
+	}
+
+	return remaining
+}
+
+// withoutOutages removes the failing and recovered listings already covered by a retailer outage starting or ending
+func (l ListingAlerts) withoutOutages(outages []RetailerOutage, recovered []*Retailer) ListingAlerts {
+	down := retailersDown(outages)
+	for _, retailer := range recovered {
+		down[retailer] = true
+	}
+
+	var failing []FailingListing
+	for _, f := range l.Failing {
+		if !down[f.Failure.Retailer] {
+			failing = append(failing, f)
+		}
+	}
+
+	var recoveredListings []RecoveredListing
+	for _, r := range l.Recovered {
+		if !down[r.Retailer] {
+			recoveredListings = append(recoveredListings, r)
+		}
+	}
+
+	return ListingAlerts{Failing: failing, Recovered: recoveredListings}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDetectOutages(t *testing.T) {
+	down := &Retailer{Name: "Down Retailer"}
+	flaky := &Retailer{Name: "Flaky Retailer"}
+	small := &Retailer{Name: "Small Retailer"}
+	products := []*Product{{Name: "Product 1"}, {Name: "Product 2"}, {Name: "Product 3"}, {Name: "Product 4"}}
+
+	blocked := &HTTPError{StatusCode: 503, Err: errors.New("Service Unavailable")}
+	notFound := &HTTPError{StatusCode: 404, Err: errors.New("Not Found")}
+
+	prices := map[*Product][]SuccessScrape{
+		products[0]: {{Retailer: down, Price: 10.00}, {Retailer: flaky, Price: 10.00}},
+		products[1]: {{Retailer: flaky, Price: 10.00}},
+	}
+	failures := []FailedScrape{
+		{Product: products[1], Retailer: down, Error: blocked},
+		{Product: products[2], Retailer: down, Error: blocked},
+		{Product: products[3], Retailer: down, Error: blocked},
+		{Product: products[2], Retailer: flaky, Error: blocked},
+		{Product: products[3], Retailer: flaky, Error: notFound},
+		{Product: products[0], Retailer: small, Error: blocked},
+	}
+
+	outages := DetectOutages(prices, failures, Outages{})
+
+	// Down Retailer has 3 of 4 listings failing the same way. Flaky Retailer has half failing, split across two
+	// classes, and Small Retailer has too few listings to tell.
+	if len(outages) != 1 {
+		t.Fatalf("expected 1 outage, got %+v", outages)
+	}
+	if outage := outages[0]; outage.Retailer != down || outage.Class != ErrorClassBlocked || outage.Failed != 3 || outage.Total != 4 {
+		t.Errorf("unexpected outage: %+v", outage)
+	}
+
+	outages = DetectOutages(prices, failures, Outages{MinListings: 1, FailureRatio: 0.25})
+	if len(outages) != 3 {
+		t.Errorf("expected every retailer to be down with the lower thresholds, got %+v", outages)
+	}
+}
+
+func TestUpdateOutages(t *testing.T) {
+	cache := newTestCache(t)
+	retailer := &Retailer{Name: "Test Retailer"}
+	product := &Product{Name: "Test Product"}
+	outages := []RetailerOutage{{Retailer: retailer, Class: ErrorClassBlocked, Failed: 2, Total: 2}}
+	updateOutages := func(outages []RetailerOutage, prices map[*Product][]SuccessScrape) OutageUpdate {
+		update, err := UpdateOutages(cache, outages, prices)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		err = cache.SetOutages(update.Started, update.Recovered)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return update
+	}
+
+	// Not stored, e.g. as notifying it failed => found again
+	for run := 1; run <= 2; run++ {
+		update, err := UpdateOutages(cache, outages, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(update.Started) != 1 {
+			t.Errorf("run %d: expected the outage to start, got %+v", run, update)
+		}
+	}
+
+	update := updateOutages(outages, nil)
+	if len(update.Started) != 1 || len(update.Recovered) != 0 {
+		t.Errorf("expected the outage to start, got %+v", update)
+	}
+
+	// An ongoing outage is only notified once
+	update = updateOutages(outages, nil)
+	if !update.Empty() {
+		t.Errorf("expected no update for an ongoing outage, got %+v", update)
+	}
+
+	// A different class of error is a new outage
+	outages[0].Class = ErrorClassSelector
+	update = updateOutages(outages, nil)
+	if len(update.Started) != 1 {
+		t.Errorf("expected the outage to restart, got %+v", update)
+	}
+
+	// Without an outage or a successful scrape the retailer hasn't recovered yet
+	update = updateOutages(nil, nil)
+	if !update.Empty() {
+		t.Errorf("expected no update without a successful scrape, got %+v", update)
+	}
+
+	prices := map[*Product][]SuccessScrape{product: {{Retailer: retailer, Price: 10.00}}}
+	update = updateOutages(nil, prices)
+	if len(update.Recovered) != 1 || update.Recovered[0] != retailer {
+		t.Errorf("expected the retailer to recover, got %+v", update)
+	}
+
+	update = updateOutages(nil, prices)
+	if !update.Empty() {
+		t.Errorf("expected no update after recovering, got %+v", update)
+	}
+}
+
+func TestListingAlertsWithoutOutages(t *testing.T) {
+	down := &Retailer{Name: "Down Retailer"}
+	recovered := &Retailer{Name: "Recovered Retailer"}
+	other := &Retailer{Name: "Other Retailer"}
+	product := &Product{Name: "Test Product"}
+
+	alerts := ListingAlerts{
+		Failing: []FailingListing{
+			{Failure: FailedScrape{Product: product, Retailer: down}, Count: 3},
+			{Failure: FailedScrape{Product: product, Retailer: other}, Count: 3},
+		},
+		Recovered: []RecoveredListing{
+			{Product: product, Retailer: recovered, Failures: 5},
+			{Product: product, Retailer: other, Failures: 3},
+		},
+	}
+
+	filtered := alerts.withoutOutages([]RetailerOutage{{Retailer: down}}, []*Retailer{recovered})
+	if len(filtered.Failing) != 1 || filtered.Failing[0].Failure.Retailer != other {
+		t.Errorf("expected only the other retailer's failure, got %+v", filtered.Failing)
+	}
+	if len(filtered.Recovered) != 1 || filtered.Recovered[0].Retailer != other {
+		t.Errorf("expected only the other retailer's recovery, got %+v", filtered.Recovered)
+	}
+}
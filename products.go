@@ -4,27 +4,50 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"strings"
+	"sort"
 )
 
 type Product struct {
-	Name          string
-	BasePrice     float64
-	Category      string
-	RetailerLinks map[*Retailer]string
+	Name      string
+	BasePrice float64
+	Category  string
+	// The pack size the base price is for, in the base unit (ml, g or count)
+	Size         float64
+	Unit         string
+	SellerPolicy SellerPolicy
+	Links        []*Link
+
+	Rules         NotifyRules
+	CategoryRules NotifyRules
 }
+
+type Link struct {
+	Retailer *Retailer
+	Url      string
+	Label    string
+	Size     float64
+	Unit     string
+}
+
 type Products []Product
 
 type FailedScrape struct {
 	Product  *Product
 	Retailer *Retailer
+	Label    string
+	Url      string
 	Error    error
 }
 
+func (f FailedScrape) Key() CacheKey {
+	return CacheKey{Retailer: f.Retailer.Name, Product: f.Product.Name, Label: f.Label}
+}
+
 func (f FailedScrape) LogValue() slog.Value {
 	return slog.GroupValue(
 		slog.String("product", f.Product.Name),
-		slog.String("retailer", f.Retailer.Name),
+		slog.String("retailer", listingName(f.Retailer, f.Label)),
+		slog.String("class", string(ClassifyError(f.Error))),
 		slog.String("err", f.Error.Error()),
 	)
 }
@@ -40,64 +63,231 @@ func (fs FailedScrapes) LogValue() slog.Value {
 }
 
 type SuccessScrape struct {
-	Retailer    *Retailer
-	Price       float64
-	Url         string
-	CachedPrice *float64
+	Retailer     *Retailer
+	Price        float64
+	Url          string
+	CachedPrice  *float64
+	Label        string
+	Size         float64
+	Unit         string
+	FinalUrl     string
+	Title        string
+	CanonicalUrl string
+	PriceType    PriceType
+	// The price without a loyalty card, when Price is a member price
+	StandardPrice float64
+	Promotions    []string
+	Seller        string
+	Fulfilment    Fulfilment
+	OutOfStock    bool
+}
+
+// setPrice records the price we would pay from a scrape, keeping the standard price when it's a member price
+func (s *SuccessScrape) setPrice(result ScrapeResult) {
+	s.Price, s.PriceType = s.Retailer.PriceFor(result)
+	s.StandardPrice = 0
+	if s.PriceType == PriceTypeMember {
+		s.StandardPrice = result.Price
+	}
 }
 
-func GetProducts(config Config, retailers map[string]*Retailer) Products {
+func (s SuccessScrape) Key(product *Product) CacheKey {
+	return CacheKey{Retailer: s.Retailer.Name, Product: product.Name, Label: s.Label}
+}
+
+// listingName is the retailer's name, followed by the label distinguishing the listing from the retailer's others
+func listingName(retailer *Retailer, label string) string {
+	if label == "" {
+		return retailer.Name
+	}
+	return fmt.Sprintf("%s (%s)", retailer.Name, label)
+}
+
+func GetProducts(config Config, retailers map[string]*Retailer) (Products, error) {
 	var products []Product
 
+	categoryRules, err := GetCategoryRules(config, retailers)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, p := range config.Products {
+		size, unit, err := normaliseSize(float64(p.Size), p.Unit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size for %s: %v", p.Name, err)
+		}
+
+		sellerPolicy, err := parseSellerPolicy(p.SellerPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid seller policy for %s: %v", p.Name, err)
+		}
+
 		product := Product{
-			Name:          p.Name,
-			BasePrice:     p.BasePrice,
-			Category:      p.Category,
-			RetailerLinks: make(map[*Retailer]string),
+			Name:         p.Name,
+			BasePrice:    p.BasePrice,
+			Category:     p.Category,
+			Size:         size,
+			Unit:         unit,
+			SellerPolicy: sellerPolicy,
+		}
+
+		product.Rules, err = newNotifyRules(NotifyRulesTOML{MinDiscount: p.MinDiscount, TargetPrice: p.TargetPrice, NotifyRetailers: p.NotifyRetailers}, retailers)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rules for %s: %v", p.Name, err)
+		}
+		product.CategoryRules = categoryRules[p.Category]
+
+		links, err := p.GetLinks()
+		if err != nil {
+			return nil, err
 		}
 
-		for retailerName, link := range p.Links {
-			retailer := retailers[retailerName]
-			product.RetailerLinks[retailer] = link
+		for retailerName, retailerLinks := range links {
+			retailer, ok := retailers[retailerName]
+			if !ok {
+				return nil, fmt.Errorf("unknown retailer %s for %s", retailerName, p.Name)
+			}
+
+			labels := make(map[string]bool)
+			for _, link := range retailerLinks {
+				// The label identifies the listing in the cache, so must be unique for the retailer
+				if labels[link.Label] {
+					return nil, fmt.Errorf("%s has more than one %s link labelled %q", p.Name, retailerName, link.Label)
+				}
+				labels[link.Label] = true
+
+				if link.Unit != "" && link.Size == 0 {
+					return nil, fmt.Errorf("%s link for %s has a unit but no size", listingName(retailer, link.Label), p.Name)
+				}
+
+				// Links without a unit use the product's
+				linkUnit := link.Unit
+				if linkUnit == "" && link.Size > 0 {
+					linkUnit = p.Unit
+				}
+
+				linkSize, linkBaseUnit, err := normaliseSize(float64(link.Size), linkUnit)
+				if err != nil {
+					return nil, fmt.Errorf("invalid size for %s link for %s: %v", listingName(retailer, link.Label), p.Name, err)
+				}
+				if product.Unit != "" && linkBaseUnit != "" && linkBaseUnit != product.Unit {
+					return nil, fmt.Errorf("%s link for %s is sized in %s but the product is sized in %s", listingName(retailer, link.Label), p.Name, linkBaseUnit, product.Unit)
+				}
+
+				product.Links = append(product.Links, &Link{
+					Retailer: retailer,
+					Url:      CanonicalUrl(link.Url),
+					Label:    link.Label,
+					Size:     linkSize,
+					Unit:     linkBaseUnit,
+				})
+			}
 		}
 
+		sort.Slice(product.Links, func(i, j int) bool {
+			if product.Links[i].Retailer.Name != product.Links[j].Retailer.Name {
+				return product.Links[i].Retailer.Name < product.Links[j].Retailer.Name
+			}
+			return product.Links[i].Label < product.Links[j].Label
+		})
+
 		products = append(products, product)
 	}
 
-	return products
+	return products, nil
+}
+
+type listing struct {
+	product *Product
+	link    *Link
 }
 
-func (p Products) GetPrices(ctx context.Context, cachedPrices map[CacheKey]float64) (map[*Product][]SuccessScrape, []FailedScrape) {
+func (p Products) listingsByRetailer() ([]*Retailer, map[*Retailer][]listing) {
+	var retailers []*Retailer
+	listings := make(map[*Retailer][]listing)
+
+	for i, product := range p {
+		for _, link := range product.Links {
+			if _, ok := listings[link.Retailer]; !ok {
+				retailers = append(retailers, link.Retailer)
+			}
+			listings[link.Retailer] = append(listings[link.Retailer], listing{product: &p[i], link: link})
+		}
+	}
+
+	sort.Slice(retailers, func(i, j int) bool {
+		return retailers[i].Name < retailers[j].Name
+	})
+
+	return retailers, listings
+}
+
+func (p Products) GetPrices(ctx context.Context, cachedPrices map[CacheKey]float64, breakers *Breakers) (map[*Product][]SuccessScrape, []FailedScrape) {
 	prices := make(map[*Product][]SuccessScrape)
 	var failures []FailedScrape
 
-	for i, product := range p {
-		var successScrapes []SuccessScrape
+	scrape := func(l listing) bool {
+		result, err := l.link.Retailer.Scraper.Scrape(ctx, l.link.Url)
+		if err != nil {
+			failures = append(failures, FailedScrape{Product: l.product, Retailer: l.link.Retailer, Label: l.link.Label, Url: l.link.Url, Error: err})
+			return false
+		}
 
-		for retailer, link := range product.RetailerLinks {
-			price, err := retailer.Scraper.ExtractPrice(ctx, link)
-			if err != nil {
-				failures = append(failures, FailedScrape{Product: &p[i], Retailer: retailer, Error: err})
+		successScrape := SuccessScrape{
+			Retailer:     l.link.Retailer,
+			Url:          l.link.Url,
+			Label:        l.link.Label,
+			Size:         l.link.Size,
+			Unit:         l.link.Unit,
+			FinalUrl:     result.FinalUrl,
+			Title:        result.Title,
+			CanonicalUrl: result.CanonicalUrl,
+			Promotions:   result.Promotions,
+			Seller:       result.Seller,
+			Fulfilment:   result.Fulfilment,
+			OutOfStock:   result.OutOfStock,
+		}
+		successScrape.setPrice(result)
+		if cachedPrice, ok := cachedPrices[successScrape.Key(l.product)]; ok {
+			successScrape.CachedPrice = &cachedPrice
+		}
+
+		prices[l.product] = append(prices[l.product], successScrape)
+		return true
+	}
+
+	retailers, listings := p.listingsByRetailer()
+	for _, retailer := range retailers {
+		remaining := listings[retailer]
+
+		switch breakers.Allow(retailer) {
+		case BreakerSkip:
+			continue
+		case BreakerProbe:
+			// Try a single listing before resuming the rest of the retailer's scrapes
+			succeeded := scrape(remaining[0])
+			breakers.Probe(retailer, succeeded)
+			if !succeeded {
 				continue
 			}
+			remaining = remaining[1:]
+		}
 
-			key := CacheKey{
-				Retailer: retailer.Name,
-				Product:  product.Name,
+		var succeeded, failed int
+		for _, l := range remaining {
+			if scrape(l) {
+				succeeded++
+			} else {
+				failed++
 			}
-			cachedPrice, _ := cachedPrices[key]
-
-			successScrapes = append(successScrapes, SuccessScrape{Retailer: retailer, Price: price, Url: link, CachedPrice: &cachedPrice})
 		}
-
-		prices[&p[i]] = successScrapes
+		breakers.Record(retailer, succeeded, failed)
 	}
 
 	return prices, failures
 }
 
-func (p Products) FindPricesAndNotify(ctx context.Context, logger *slog.Logger, client Client, cache *Cache, minDiscount float64) error {
+func (p Products) FindPricesAndNotify(ctx context.Context, logger *slog.Logger, client Client, cache *Cache, config Config, baskets []Basket) error {
 	logger.Info("Starting scrape")
 
 	cachedPrices, err := cache.GetScrapes()
@@ -105,57 +295,168 @@ func (p Products) FindPricesAndNotify(ctx context.Context, logger *slog.Logger,
 		return fmt.Errorf("error getting cached prices: %v", err)
 	}
 
-	prices, failures := p.GetPrices(ctx, cachedPrices)
-	if failures != nil {
-		logger.Warn("Failures returned from getting prices", slog.Any("failures", failures))
+	breakers, err := LoadBreakers(cache, config.CircuitBreaker, logger)
+	if err != nil {
+		return fmt.Errorf("error loading circuit breakers: %v", err)
 	}
 
-	notifiablePrices := GetNotifiablePrices(prices, minDiscount)
-	if len(notifiablePrices) == 0 {
-		logger.Info("No prices found to notify")
-	} else {
-		logger.Info("Prices found to notify", slog.Any("prices", notifiablePrices))
-		err = notify(notifiablePrices, client)
+	prices, failures := p.GetPrices(ctx, cachedPrices, breakers)
+
+	err = breakers.Save(cache)
+	if err != nil {
+		return fmt.Errorf("error saving circuit breakers: %v", err)
+	}
+
+	// Report retailer-wide outages once rather than as a failure for each of their listings
+	outages := DetectOutages(prices, failures, config.Outages)
+	for _, outage := range outages {
+		logger.Warn("Retailer outage detected", slog.Any("outage", outage))
+	}
+
+	if remaining := withoutOutages(failures, outages); remaining != nil {
+		logger.Warn("Failures returned from getting prices", slog.Any("failures", FailedScrapes(remaining)))
+	}
+
+	outageUpdate, err := UpdateOutages(cache, outages, prices)
+	if err != nil {
+		return fmt.Errorf("error updating outages: %v", err)
+	}
+	// Failing to send the notifications before the deals is logged rather than ending the run, leaving what wasn't
+	// sent unstored so it's sent again next run
+	if !outageUpdate.Empty() {
+		err = notifyOutages(ctx, outageUpdate, client)
 		if err != nil {
-			return fmt.Errorf("error notifying products: %v", err)
+			LogError(logger, "Failed to notify outages", err)
+		}
+	}
+	if err == nil {
+		err = cache.SetOutages(outageUpdate.Started, outageUpdate.Recovered)
+		if err != nil {
+			return fmt.Errorf("error storing outages: %v", err)
 		}
 	}
 
-	return cache.SetScrapes(prices)
-}
+	healthIssues, newHealthIssues, pageUpdates, err := CheckListingHealth(cache, prices, failures)
+	if err != nil {
+		return fmt.Errorf("error checking listing health: %v", err)
+	}
+	if healthIssues != nil {
+		logger.Warn("Listing health issues found", slog.Any("issues", ListingHealths(healthIssues)))
+	}
+	if newHealthIssues != nil {
+		err = notifyListingHealth(ctx, newHealthIssues, client)
+		if err != nil {
+			LogError(logger, "Failed to notify listing health", err)
+		}
+	}
+	if err == nil {
+		err = cache.SetPageRecords(pageUpdates)
+		if err != nil {
+			return fmt.Errorf("error storing listing pages: %v", err)
+		}
+	}
 
-func (p Product) getDiscountString(price float64) string {
-	discount := p.BasePrice - price
-	percentage := discount / p.BasePrice * 100
-	return fmt.Sprintf("(-£%.2f | %.2f%% off)", discount, percentage)
-}
+	err = p.RecordCanonicalUrls(cache, prices, healthIssues)
+	if err != nil {
+		return fmt.Errorf("error recording canonical URLs: %v", err)
+	}
 
-func (s *SuccessScrape) GetCheapestPriceString(product *Product) string {
-	return s.formatPriceString(product, "Best price: ", true)
-}
+	alerts, failureUpdate, err := TrackFailures(cache, prices, failures, config.General.FailureAlertThreshold)
+	if err != nil {
+		return fmt.Errorf("error tracking failures: %v", err)
+	}
+	alerts = alerts.withoutOutages(outages, outageUpdate.Recovered)
+	if !alerts.Empty() {
+		err = notifyListingAlerts(ctx, alerts, client)
+		if err != nil {
+			LogError(logger, "Failed to notify listing alerts", err)
+		}
+	}
+	// Only stored once notified, as alerts are sent when a count reaches the threshold so would otherwise be lost
+	if err == nil {
+		err = cache.SetFailures(failureUpdate)
+		if err != nil {
+			return fmt.Errorf("error storing failures: %v", err)
+		}
+	}
 
-func (s *SuccessScrape) GetOtherPriceString(product *Product) string {
-	return s.formatPriceString(product, "- ", false)
-}
+	prices, rejected := CheckSellers(prices)
+	if rejected != nil {
+		logger.Warn("Offers rejected by seller policy", slog.Any("rejected", RejectedScrapes(rejected)))
+		err = cache.AddRejectedScrapes(rejected)
+		if err != nil {
+			return fmt.Errorf("error storing rejected offers: %v", err)
+		}
+	}
 
-func (s *SuccessScrape) formatPriceString(product *Product, prefix string, bold bool) string {
-	var output strings.Builder
+	prices, rejected = config.Sanity.CheckPrices(ctx, prices)
+	if rejected != nil {
+		logger.Warn("Prices rejected by sanity checks", slog.Any("rejected", RejectedScrapes(rejected)))
+		err = cache.AddRejectedScrapes(rejected)
+		if err != nil {
+			return fmt.Errorf("error storing rejected prices: %v", err)
+		}
+	}
 
-	output.WriteString(prefix)
+	promotions, promotionUpdates, err := FindNewPromotions(cache, prices)
+	if err != nil {
+		return fmt.Errorf("error finding promotions: %v", err)
+	}
+	if promotions != nil {
+		logger.Info("New promotions found", slog.Int("count", len(promotions)))
+		err = notifyPromotions(ctx, promotions, client)
+		if err != nil {
+			LogError(logger, "Failed to notify promotions", err)
+		}
+	}
+	if err == nil {
+		err = cache.SetPromotions(promotionUpdates)
+		if err != nil {
+			return fmt.Errorf("error storing promotions: %v", err)
+		}
+	}
 
-	switch {
-	case s.CachedPrice == nil:
-		output.WriteString("🆕 ")
-	case s.Price > *s.CachedPrice:
-		output.WriteString("🔺 ")
+	rules, err := GetAlertRules(config)
+	if err != nil {
+		return fmt.Errorf("error getting alert rules: %v", err)
+	}
+	matches, ruleResults, err := EvaluateAlertRules(cache, rules, prices)
+	if err != nil {
+		return fmt.Errorf("error evaluating alert rules: %v", err)
+	}
+	if matches != nil {
+		logger.Info("Alert rules matched", slog.Int("count", len(matches)))
+		err = notifyRuleMatches(ctx, matches, client)
+		if err != nil {
+			LogError(logger, "Failed to notify alert rules", err)
+		}
+	}
+	// Only stored once notified, so matches that failed to send are sent again next run
+	if err == nil {
+		err = cache.SetRuleMatches(ruleResults)
+		if err != nil {
+			return fmt.Errorf("error storing rule matches: %v", err)
+		}
 	}
 
-	priceFormat := "£%.2f"
-	if bold {
-		priceFormat = "**£%.2f**"
+	var notifiablePrices map[*Product][]SuccessScrape
+	if !config.General.RulesOnly {
+		notifiablePrices = GetNotifiablePrices(prices, config.General.MinDiscount, config.General.UseEffectivePrice)
+	}
+	if len(notifiablePrices) == 0 {
+		logger.Info("No prices found to notify")
+	} else {
+		logger.Info("Prices found to notify", slog.Any("prices", notifiablePrices))
+		err = notify(ctx, notifiablePrices, client)
+		if err != nil {
+			return fmt.Errorf("error notifying products: %v", err)
+		}
 	}
 
-	output.WriteString(fmt.Sprintf(priceFormat+" at [%s](%s) %s", s.Price, s.Retailer.Name, s.Url, product.getDiscountString(s.Price)))
+	err = cache.SetScrapes(prices)
+	if err != nil {
+		return fmt.Errorf("error caching prices: %v", err)
+	}
 
-	return output.String()
+	return NotifyBaskets(ctx, baskets, cache, client)
 }
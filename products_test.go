@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pelletier/go-toml"
+)
+
+func loadTestProducts(t *testing.T, data string) (Products, error) {
+	var config Config
+	err := toml.Unmarshal([]byte(data), &config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	retailers, err := GetRetailers(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return GetProducts(config, retailers)
+}
+
+func TestGetProductsLinkLabels(t *testing.T) {
+	products, err := loadTestProducts(t, `
+[[products]]
+    name = "Serum"
+    base_price = 10.0
+
+    [products.links]
+    boots = ["https://www.boots.com/serum-1", "https://www.boots.com/serum-2", { url = "https://www.boots.com/serum-3", label = "Twin pack" }]
+    superdrug = ["https://www.superdrug.com/serum"]
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var labels []string
+	for _, link := range products[0].Links {
+		labels = append(labels, link.Retailer.Name+":"+link.Label)
+	}
+
+	// Unlabelled links in a list are labelled by their position, while a single link needs no label
+	expected := "Boots:1,Boots:2,Boots:Twin pack,Superdrug:"
+	if actual := strings.Join(labels, ","); actual != expected {
+		t.Errorf("unexpected labels: expected %s, got %s", expected, actual)
+	}
+}
+
+func TestGetProductsDuplicateLabels(t *testing.T) {
+	_, err := loadTestProducts(t, `
+[[products]]
+    name = "Serum"
+    base_price = 10.0
+
+    [products.links]
+    boots = ["https://www.boots.com/serum-1", { url = "https://www.boots.com/serum-2", label = "1" }]
+`)
+	if err == nil || !strings.Contains(err.Error(), `more than one boots link labelled "1"`) {
+		t.Errorf("expected a duplicate label error, got %v", err)
+	}
+}
+
+func TestGetProductsUnitWithoutSize(t *testing.T) {
+	_, err := loadTestProducts(t, `
+[[products]]
+    name = "Serum"
+    base_price = 10.0
+    size = 30
+    unit = "ml"
+
+    [products.links]
+    boots = { url = "https://www.boots.com/serum", unit = "ml" }
+`)
+	if err == nil || !strings.Contains(err.Error(), "Boots link for Serum has a unit but no size") {
+		t.Errorf("expected a missing size error, got %v", err)
+	}
+}
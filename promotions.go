@@ -0,0 +1,141 @@
+package main
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type Promotion struct {
+	Text string
+	// The price of each item once the promotion is applied, if the promotion could be understood
+	EffectivePrice *float64
+}
+
+type NewPromotion struct {
+	Product   *Product
+	Scrape    SuccessScrape
+	Promotion Promotion
+}
+
+var (
+	buyGetPattern     = regexp.MustCompile(`buy (one|two|three|\d+),? get (one|two|three|\d+) (free|half price)`)
+	multiBuyPattern   = regexp.MustCompile(`\b(\d+) for (\d+)\b`)
+	bundlePattern     = regexp.MustCompile(`\b(\d+) for £(\d+(?:\.\d{1,2})?)`)
+	spendPattern      = regexp.MustCompile(`£(\d+(?:\.\d{1,2})?) off (?:when you spend|orders over|when you spend over) £(\d+(?:\.\d{1,2})?)`)
+	percentOffPattern = regexp.MustCompile(`\b(\d+)% off`)
+)
+
+var numberWords = map[string]float64{"one": 1, "two": 2, "three": 3}
+
+func parseQuantity(s string) float64 {
+	if n, ok := numberWords[s]; ok {
+		return n
+	}
+	n, _ := strconv.ParseFloat(s, 64)
+	return n
+}
+
+// normalisePromotion tidies the whitespace of promotion text scraped from a page, so it can be compared between scrapes
+func normalisePromotion(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// ParsePromotion works out the price of each item once a promotion such as "3 for 2" is applied, returning nil if
+// the promotion isn't understood
+func ParsePromotion(text string, price float64) *float64 {
+	text = strings.ToLower(text)
+
+	var effective float64
+	switch {
+	case strings.Contains(text, "bogof"):
+		effective = price / 2
+	case buyGetPattern.MatchString(text):
+		matches := buyGetPattern.FindStringSubmatch(text)
+		bought, free := parseQuantity(matches[1]), parseQuantity(matches[2])
+		if bought <= 0 || free <= 0 {
+			return nil
+		}
+		discount := 1.0
+		if matches[3] == "half price" {
+			discount = 0.5
+		}
+		effective = price * (bought + free*(1-discount)) / (bought + free)
+	case bundlePattern.MatchString(text):
+		matches := bundlePattern.FindStringSubmatch(text)
+		count := parseQuantity(matches[1])
+		total, _ := strconv.ParseFloat(matches[2], 64)
+		if count <= 0 {
+			return nil
+		}
+		effective = total / count
+	case multiBuyPattern.MatchString(text):
+		matches := multiBuyPattern.FindStringSubmatch(text)
+		count, paid := parseQuantity(matches[1]), parseQuantity(matches[2])
+		if count <= 0 || paid <= 0 || paid >= count {
+			return nil
+		}
+		effective = price * paid / count
+	case spendPattern.MatchString(text):
+		matches := spendPattern.FindStringSubmatch(text)
+		off, _ := strconv.ParseFloat(matches[1], 64)
+		spend, _ := strconv.ParseFloat(matches[2], 64)
+		if price <= 0 {
+			return nil
+		}
+		// Buy just enough of the product to reach the minimum spend
+		count := math.Ceil(spend/price - 0.0001)
+		effective = (count*price - off) / count
+	case percentOffPattern.MatchString(text):
+		matches := percentOffPattern.FindStringSubmatch(text)
+		percent := parseQuantity(matches[1])
+		if percent <= 0 || percent >= 100 {
+			return nil
+		}
+		effective = price * (1 - percent/100)
+	default:
+		return nil
+	}
+
+	effective = math.Round(effective*100) / 100
+	if effective >= price {
+		return nil
+	}
+	return &effective
+}
+
+// FindNewPromotions returns the promotions shown on each scraped listing that weren't there on the previous scrape,
+// along with every listing's promotions to store with Cache.SetPromotions once they've been notified. A listing
+// without any stored promotions yet, such as one just added, has its promotions recorded without being notified.
+func FindNewPromotions(cache *Cache, prices map[*Product][]SuccessScrape) ([]NewPromotion, map[CacheKey][]string, error) {
+	known, err := cache.GetPromotions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var found []NewPromotion
+	updates := make(map[CacheKey][]string)
+	for product, scrapes := range prices {
+		for _, scrape := range scrapes {
+			key := scrape.Key(product)
+			updates[key] = nil
+			_, checked := known[key]
+
+			for _, text := range scrape.Promotions {
+				updates[key] = append(updates[key], text)
+				if !checked || known[key][text] {
+					continue
+				}
+
+				found = append(found, NewPromotion{
+					Product:   product,
+					Scrape:    scrape,
+					Promotion: Promotion{Text: text, EffectivePrice: ParsePromotion(text, scrape.Price)},
+				})
+			}
+		}
+	}
+
+	return found, updates, nil
+}
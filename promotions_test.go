@@ -0,0 +1,103 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParsePromotion(t *testing.T) {
+	tests := []struct {
+		text     string
+		price    float64
+		expected float64
+	}{
+		{"3 for 2 on selected skincare", 9.00, 6.00},
+		{"Buy one get one free", 10.00, 5.00},
+		{"Buy one get one half price", 10.00, 7.50},
+		{"Buy 2 get 1 free", 6.00, 4.00},
+		{"BOGOF", 4.00, 2.00},
+		{"2 for £15", 10.00, 7.50},
+		{"£5 off when you spend £30", 12.00, 10.33},
+		{"25% off", 8.00, 6.00},
+		// Not understood => no effective price
+		{"Free gift with purchase", 10.00, 0},
+		// Doesn't make the product cheaper => no effective price
+		{"2 for £25", 10.00, 0},
+	}
+
+	for _, test := range tests {
+		effective := ParsePromotion(test.text, test.price)
+		if test.expected == 0 {
+			if effective != nil {
+				t.Errorf("%s: expected no effective price, got %.2f", test.text, *effective)
+			}
+			continue
+		}
+
+		if effective == nil {
+			t.Errorf("%s: expected %.2f, got no effective price", test.text, test.expected)
+		} else if math.Abs(*effective-test.expected) > 0.001 {
+			t.Errorf("%s: expected %.2f, got %.2f", test.text, test.expected, *effective)
+		}
+	}
+}
+
+func TestFindNewPromotions(t *testing.T) {
+	cache := newTestCache(t)
+	product := &Product{Name: "Test Product"}
+	retailer := &Retailer{Name: "Test Retailer"}
+	prices := map[*Product][]SuccessScrape{product: {{Retailer: retailer, Price: 9.00, Url: "https://test.com/1", Promotions: []string{"10% off"}}}}
+
+	findNewPromotions := func() []NewPromotion {
+		found, updates, err := FindNewPromotions(cache, prices)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		err = cache.SetPromotions(updates)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return found
+	}
+
+	// A listing's first scrape only records the promotions already on it
+	found := findNewPromotions()
+	if len(found) != 0 {
+		t.Errorf("expected no new promotions on the first scrape, got %+v", found)
+	}
+
+	prices[product][0].Promotions = []string{"10% off", "3 for 2"}
+	found = findNewPromotions()
+	if len(found) != 1 || found[0].Promotion.EffectivePrice == nil || *found[0].Promotion.EffectivePrice != 6.00 {
+		t.Fatalf("expected the new 3 for 2 promotion, got %+v", found)
+	}
+
+	// Seen on the previous scrape => not new
+	found = findNewPromotions()
+	if len(found) != 0 {
+		t.Errorf("expected no new promotions, got %+v", found)
+	}
+
+	// Removed and then shown again => new again
+	prices[product][0].Promotions = nil
+	findNewPromotions()
+
+	prices[product][0].Promotions = []string{"3 for 2"}
+	found = findNewPromotions()
+	if len(found) != 1 {
+		t.Errorf("expected the promotion to be new again, got %+v", found)
+	}
+
+	// Not stored, e.g. as notifying it failed => new again on the next run
+	prices[product][0].Promotions = []string{"3 for 2", "Buy 1 get 1 free"}
+	for run := 1; run <= 2; run++ {
+		found, _, err := FindNewPromotions(cache, prices)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(found) != 1 {
+			t.Errorf("run %d: expected the promotion to be new, got %+v", run, found)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type PushPriority int
+
+const (
+	PushPriorityLow PushPriority = iota
+	PushPriorityDefault
+	PushPriorityHigh
+	PushPriorityUrgent
+)
+
+// dealPriority raises a deal's priority with the size of its discount, so only the best deals interrupt
+func dealPriority(discountPct float64) PushPriority {
+	switch {
+	case discountPct >= 50:
+		return PushPriorityUrgent
+	case discountPct >= 30:
+		return PushPriorityHigh
+	case discountPct >= 10:
+		return PushPriorityDefault
+	default:
+		return PushPriorityLow
+	}
+}
+
+// pushMessage is a single push notification, sent for each deal or for the whole of any other notification
+type pushMessage struct {
+	Title    string
+	Message  string
+	Priority PushPriority
+	Category string
+	// Opened when the notification is tapped
+	Url      string
+	Markdown bool
+}
+
+func newPushMessages(notification Notification, emoji map[string]string) []pushMessage {
+	if notification.Kind != NotificationDeals {
+		var message strings.Builder
+		renderSectionsMarkdown(&message, escapeMarkdown(notification))
+
+		return []pushMessage{{
+			Title:    fmt.Sprintf("%s %s", notification.Icon, notification.Title),
+			Message:  strings.TrimSpace(message.String()),
+			Priority: PushPriorityDefault,
+			Markdown: true,
+		}}
+	}
+
+	var messages []pushMessage
+	for _, category := range notification.Categories {
+		for _, deal := range category.Deals {
+			best := deal.Prices[0]
+
+			icon, ok := emoji[deal.Category]
+			if !ok {
+				icon = notification.Icon
+			}
+
+			lines := []string{fmt.Sprintf("Base price: £%.2f", deal.BasePrice)}
+			for _, price := range deal.Prices {
+				lines = append(lines, fmt.Sprintf("%s£%.2f%s at %s (%s)", priceChangeMarker(price.Change), price.Price,
+					priceExtras(price), price.Listing, discountText(price)))
+			}
+
+			messages = append(messages, pushMessage{
+				Title:    fmt.Sprintf("%s %s: £%.2f (%.0f%% off)", icon, deal.Product, best.Price, best.DiscountPct),
+				Message:  strings.Join(lines, "\n"),
+				Priority: dealPriority(best.DiscountPct),
+				Category: deal.Category,
+				Url:      best.Url,
+			})
+		}
+	}
+
+	return messages
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func pushTestPrices() map[*Product][]SuccessScrape {
+	retailer := &Retailer{Name: "Test Retailer"}
+	otherRetailer := &Retailer{Name: "Other Retailer"}
+
+	return map[*Product][]SuccessScrape{
+		&Product{Name: "Serum", BasePrice: 10.00, Category: "skincare"}: {
+			{Retailer: otherRetailer, Price: 8.00, Url: "https://other.com/1", CachedPrice: floatPtr(8.00)},
+			{Retailer: retailer, Price: 4.00, Url: "https://test.com/1", CachedPrice: floatPtr(5.00)},
+		},
+		&Product{Name: "Shampoo", BasePrice: 10.00, Category: "haircare"}: {
+			{Retailer: retailer, Price: 9.50, Url: "https://test.com/2", CachedPrice: floatPtr(9.80)},
+		},
+	}
+}
+
+func TestNtfyClient(t *testing.T) {
+	var messages []ntfyMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.Header.Get("Authorization"); token != "Bearer token" {
+			t.Errorf("unexpected authorization header: %s", token)
+		}
+
+		var message ntfyMessage
+		err := json.NewDecoder(r.Body).Decode(&message)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		messages = append(messages, message)
+	}))
+	defer server.Close()
+
+	client, err := NewNtfyClient(Push{Server: server.URL, Topic: "prices", Token: "token", Emoji: map[string]string{"skincare": "🧴"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = notify(context.Background(), pushTestPrices(), client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(messages) != 2 {
+		t.Fatalf("expected a message for each deal, got %d", len(messages))
+	}
+
+	// Categories are sorted, so haircare comes first
+	shampoo, serum := messages[0], messages[1]
+	if serum.Topic != "prices" || serum.Title != "🧴 Serum: £4.00 (60% off)" || serum.Click != "https://test.com/1" {
+		t.Errorf("unexpected message: %+v", serum)
+	}
+	if serum.Priority != 5 || len(serum.Tags) != 1 || serum.Tags[0] != "skincare" {
+		t.Errorf("unexpected priority or tags: %+v", serum)
+	}
+	expected := "Base price: £10.00\n£4.00 at Test Retailer (-£6.00 | 60.00% off)\n£8.00 at Other Retailer (-£2.00 | 20.00% off)"
+	if serum.Message != expected {
+		t.Errorf("expected %q, got %q", expected, serum.Message)
+	}
+
+	if shampoo.Title != "🛍️ Shampoo: £9.50 (5% off)" || shampoo.Priority != 2 {
+		t.Errorf("unexpected message: %+v", shampoo)
+	}
+}
+
+func TestGotifyClient(t *testing.T) {
+	var messages []gotifyMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/message" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if token := r.Header.Get("X-Gotify-Key"); token != "token" {
+			t.Errorf("unexpected token: %s", token)
+		}
+
+		var message gotifyMessage
+		err := json.NewDecoder(r.Body).Decode(&message)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		messages = append(messages, message)
+	}))
+	defer server.Close()
+
+	client, err := NewGotifyClient(Push{Server: server.URL + "/", Token: "token"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = notify(context.Background(), pushTestPrices(), client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(messages) != 2 {
+		t.Fatalf("expected a message for each deal, got %d", len(messages))
+	}
+
+	serum := messages[1]
+	if serum.Title != "🛍️ Serum: £4.00 (60% off)" || serum.Priority != 9 {
+		t.Errorf("unexpected message: %+v", serum)
+	}
+	click, _ := serum.Extras["client::notification"].(map[string]interface{})["click"].(map[string]interface{})
+	if click["url"] != "https://test.com/1" {
+		t.Errorf("unexpected extras: %+v", serum.Extras)
+	}
+
+	messages = nil
+	err = client.Notify(context.Background(), Notification{
+		Kind:     NotificationOutages,
+		Icon:     "⚠️",
+		Title:    "Retailer outages",
+		Sections: []Section{{Items: []Item{{Name: "Boots", Detail: "5 of 5 listings failed"}}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(messages) != 1 || messages[0].Message != "- **Boots**: 5 of 5 listings failed" || messages[0].Priority != 5 {
+		t.Fatalf("unexpected messages: %+v", messages)
+	}
+	if _, ok := messages[0].Extras["client::display"]; !ok {
+		t.Errorf("expected markdown to be enabled: %+v", messages[0].Extras)
+	}
+}
+
+func TestDealPriority(t *testing.T) {
+	tests := []struct {
+		discountPct float64
+		expected    PushPriority
+	}{
+		{discountPct: 5, expected: PushPriorityLow},
+		{discountPct: 10, expected: PushPriorityDefault},
+		{discountPct: 35, expected: PushPriorityHigh},
+		{discountPct: 50, expected: PushPriorityUrgent},
+	}
+
+	for _, test := range tests {
+		if priority := dealPriority(test.discountPct); priority != test.expected {
+			t.Errorf("expected priority %d for %.0f%% off, got %d", test.expected, test.discountPct, priority)
+		}
+	}
+}
@@ -1,15 +1,77 @@
 package main
 
+import "fmt"
+
 type Retailer struct {
 	Name    string
 	Scraper Scraper
+
+	DeliveryCharge        float64
+	FreeDeliveryThreshold float64
+	ClickAndCollect       bool
+	CollectionFee         float64
+
+	// Whether we hold the retailer's loyalty card, so its member prices apply
+	Member bool
+	// Whether the retailer sells offers from other sellers, so products' seller policies apply
+	Marketplace bool
 }
 
-func GetRetailers() map[string]*Retailer {
-	return map[string]*Retailer{
+type PriceType string
+
+const (
+	PriceTypeStandard PriceType = "standard"
+	PriceTypeMember   PriceType = "member"
+)
+
+func GetRetailers(config Config) (map[string]*Retailer, error) {
+	retailers := map[string]*Retailer{
 		"boots":         {Name: "Boots", Scraper: NewBootsScraper()},
-		"amazon":        {Name: "Amazon", Scraper: NewAmazonScraper()},
+		"amazon":        {Name: "Amazon", Scraper: NewAmazonScraper(), Marketplace: true},
 		"lookFantastic": {Name: "Look Fantastic", Scraper: NewLookFantasticScraper()},
 		"superdrug":     {Name: "Superdrug", Scraper: NewSuperdrugScraper()},
 	}
+
+	for name, r := range config.Retailers {
+		retailer, ok := retailers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown retailer %s", name)
+		}
+
+		retailer.DeliveryCharge = float64(r.DeliveryCharge)
+		retailer.FreeDeliveryThreshold = float64(r.FreeDeliveryThreshold)
+		retailer.ClickAndCollect = r.ClickAndCollect
+		retailer.CollectionFee = float64(r.CollectionFee)
+		retailer.Member = r.Member
+	}
+
+	return retailers, nil
+}
+
+// DeliveryCost is the delivery charge for an order of the given value
+func (r *Retailer) DeliveryCost(subtotal float64) float64 {
+	// Allow for rounding when the subtotal is summed from several prices
+	if r.FreeDeliveryThreshold > 0 && subtotal >= r.FreeDeliveryThreshold-0.005 {
+		return 0
+	}
+	return r.DeliveryCharge
+}
+
+// FulfilmentCost is the cheapest way to get an order of the given value, returning whether it is collected in store
+// rather than delivered
+func (r *Retailer) FulfilmentCost(subtotal float64) (float64, bool) {
+	delivery := r.DeliveryCost(subtotal)
+	if r.ClickAndCollect && r.CollectionFee < delivery {
+		return r.CollectionFee, true
+	}
+	return delivery, false
+}
+
+// PriceFor is the price we would pay for a scraped listing, which is the member price when we hold the retailer's
+// card and it's cheaper than the standard price
+func (r *Retailer) PriceFor(result ScrapeResult) (float64, PriceType) {
+	if r.Member && result.MemberPrice != nil && *result.MemberPrice < result.Price {
+		return *result.MemberPrice, PriceTypeMember
+	}
+	return result.Price, PriceTypeStandard
 }
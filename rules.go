@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+)
+
+// NotifyRules override when a product's prices are notified. Unset rules fall back to the category's, and then to
+// the general settings.
+type NotifyRules struct {
+	MinDiscount *float64
+	// Prices at or below the target are always worth notifying, whatever the discount
+	TargetPrice float64
+	// Only notify prices from these retailers, or any retailer if nil
+	Retailers map[*Retailer]bool
+}
+
+func newNotifyRules(rules NotifyRulesTOML, retailers map[string]*Retailer) (NotifyRules, error) {
+	notifyRules := NotifyRules{TargetPrice: float64(rules.TargetPrice)}
+
+	if rules.MinDiscount != nil {
+		minDiscount := float64(*rules.MinDiscount)
+		if minDiscount < 0 || minDiscount >= 1 {
+			return notifyRules, fmt.Errorf("min_discount must be between 0 and 1, got %v", minDiscount)
+		}
+		notifyRules.MinDiscount = &minDiscount
+	}
+
+	if notifyRules.TargetPrice < 0 {
+		return notifyRules, fmt.Errorf("target_price must not be negative, got %v", notifyRules.TargetPrice)
+	}
+
+	if rules.NotifyRetailers != nil {
+		notifyRules.Retailers = make(map[*Retailer]bool)
+		for _, name := range rules.NotifyRetailers {
+			retailer, ok := retailers[name]
+			if !ok {
+				return notifyRules, fmt.Errorf("unknown retailer %s in notify_retailers", name)
+			}
+			notifyRules.Retailers[retailer] = true
+		}
+	}
+
+	return notifyRules, nil
+}
+
+func GetCategoryRules(config Config, retailers map[string]*Retailer) (map[string]NotifyRules, error) {
+	categories := make(map[string]NotifyRules)
+	for name, c := range config.Categories {
+		rules, err := newNotifyRules(c, retailers)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rules for category %s: %v", name, err)
+		}
+		categories[name] = rules
+	}
+	return categories, nil
+}
+
+// notifyRules resolves the rules for the product, taking its own over its category's, and its category's over the
+// general minimum discount
+func (p *Product) notifyRules(minDiscount float64) NotifyRules {
+	rules := NotifyRules{MinDiscount: &minDiscount}
+
+	for _, override := range []NotifyRules{p.CategoryRules, p.Rules} {
+		if override.MinDiscount != nil {
+			rules.MinDiscount = override.MinDiscount
+		}
+		if override.TargetPrice > 0 {
+			rules.TargetPrice = override.TargetPrice
+		}
+		if override.Retailers != nil {
+			rules.Retailers = override.Retailers
+		}
+	}
+
+	return rules
+}
+
+func (n NotifyRules) allowsRetailer(retailer *Retailer) bool {
+	return n.Retailers == nil || n.Retailers[retailer]
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// rejectedScrapeRetention is how long rejected prices are kept in the database
+const rejectedScrapeRetention = 90 * 24 * time.Hour
+
+type RejectedScrape struct {
+	Product  *Product
+	Retailer *Retailer
+	Label    string
+	Price    float64
+	Url      string
+	Reason   string
+}
+
+func (r RejectedScrape) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("product", r.Product.Name),
+		slog.String("retailer", listingName(r.Retailer, r.Label)),
+		slog.Float64("price", r.Price),
+		slog.String("reason", r.Reason),
+	)
+}
+
+type RejectedScrapes []RejectedScrape
+
+func (rs RejectedScrapes) LogValue() slog.Value {
+	attrs := make([]slog.Value, len(rs))
+	for i, r := range rs {
+		attrs[i] = r.LogValue()
+	}
+	return slog.AnyValue(attrs)
+}
+
+// checkPrice returns a rejection reason if the price can never be valid, or whether it needs confirming by re-scraping
+func (s Sanity) checkPrice(product *Product, scrape SuccessScrape) (reason string, suspicious bool) {
+	if s.MinPrice > 0 && scrape.Price < s.MinPrice {
+		return fmt.Sprintf("price £%.2f is below the minimum of £%.2f", scrape.Price, s.MinPrice), false
+	}
+
+	if s.MaxDrop <= 0 {
+		return "", false
+	}
+
+	price := scrape.ComparablePrice(product)
+	reference := product.BasePrice
+	if cachedPrice := scrape.ComparableCachedPrice(product); cachedPrice != nil {
+		reference = *cachedPrice
+	}
+
+	return "", reference > 0 && 1-price/reference > s.MaxDrop
+}
+
+func (s Sanity) CheckPrices(ctx context.Context, prices map[*Product][]SuccessScrape) (map[*Product][]SuccessScrape, []RejectedScrape) {
+	type pendingScrape struct {
+		product *Product
+		index   int
+	}
+
+	var (
+		rejected []RejectedScrape
+		pending  []pendingScrape
+	)
+	checked := make(map[*Product][]SuccessScrape)
+
+	for product, scrapes := range prices {
+		for _, scrape := range scrapes {
+			reason, suspicious := s.checkPrice(product, scrape)
+			if reason != "" {
+				rejected = append(rejected, newRejectedScrape(product, scrape, reason))
+				continue
+			}
+
+			checked[product] = append(checked[product], scrape)
+			if suspicious {
+				pending = append(pending, pendingScrape{product: product, index: len(checked[product]) - 1})
+			}
+		}
+	}
+
+	if len(pending) == 0 {
+		return checked, rejected
+	}
+
+	// Give the retailer a moment before confirming, so a transient page state isn't simply scraped again
+	select {
+	case <-time.After(s.ConfirmDelay):
+	case <-ctx.Done():
+	}
+
+	discarded := make(map[*Product]map[int]bool)
+	for _, p := range pending {
+		scrape := &checked[p.product][p.index]
+		suspiciousScrape := *scrape
+
+		reason, keep := s.confirmPrice(ctx, p.product, scrape)
+		if reason != "" {
+			rejected = append(rejected, newRejectedScrape(p.product, suspiciousScrape, reason))
+		}
+		if !keep {
+			if discarded[p.product] == nil {
+				discarded[p.product] = make(map[int]bool)
+			}
+			discarded[p.product][p.index] = true
+		}
+	}
+
+	for product, indexes := range discarded {
+		var remaining []SuccessScrape
+		for i, scrape := range checked[product] {
+			if !indexes[i] {
+				remaining = append(remaining, scrape)
+			}
+		}
+
+		if len(remaining) == 0 {
+			delete(checked, product)
+		} else {
+			checked[product] = remaining
+		}
+	}
+
+	return checked, rejected
+}
+
+// confirmPrice re-scrapes a suspicious price, replacing it if the retailer now reports a believable one.
+// A rejection reason is returned whenever the original price is discarded, unless it couldn't be confirmed as the
+// context was cancelled.
+func (s Sanity) confirmPrice(ctx context.Context, product *Product, scrape *SuccessScrape) (reason string, keep bool) {
+	if ctx.Err() != nil {
+		return "", false
+	}
+
+	result, err := scrape.Retailer.Scraper.Scrape(ctx, scrape.Url)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", false
+		}
+		return fmt.Sprintf("confirmation scrape failed: %v", err), false
+	}
+	replacement := *scrape
+	replacement.setPrice(result)
+	confirmed := replacement.Price
+
+	// The offer shown may have changed seller since the first scrape, so must still meet the seller policy
+	replacement.Seller = result.Seller
+	replacement.Fulfilment = result.Fulfilment
+	if sellerReason := checkSeller(product, replacement); sellerReason != "" {
+		return fmt.Sprintf("confirmation scrape returned an %s", sellerReason), false
+	}
+
+	if math.Abs(confirmed-scrape.Price) < 0.01 {
+		return "", true
+	}
+
+	reason = fmt.Sprintf("confirmation scrape returned a different price of £%.2f", confirmed)
+
+	if rejectReason, suspicious := s.checkPrice(product, replacement); rejectReason != "" || suspicious {
+		return reason, false
+	}
+
+	*scrape = replacement
+	return reason, true
+}
+
+func newRejectedScrape(product *Product, scrape SuccessScrape, reason string) RejectedScrape {
+	return RejectedScrape{
+		Product:  product,
+		Retailer: scrape.Retailer,
+		Label:    scrape.Label,
+		Price:    scrape.Price,
+		Url:      scrape.Url,
+		Reason:   reason,
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type TestScraper struct {
+	prices map[string]float64
+	// Full results for pages that need more than a price
+	results map[string]ScrapeResult
+}
+
+func (t *TestScraper) Scrape(ctx context.Context, url string) (ScrapeResult, error) {
+	if result, ok := t.results[url]; ok {
+		result.FinalUrl = url
+		return result, nil
+	}
+	return ScrapeResult{Price: t.prices[url], FinalUrl: url}, nil
+}
+
+func TestCheckPrices(t *testing.T) {
+	product := &Product{
+		Name:      "Test Product",
+		BasePrice: 10.00,
+	}
+	retailer := &Retailer{
+		Name: "Test Retailer",
+		Scraper: &TestScraper{prices: map[string]float64{
+			"https://test.com/3": 3.00,
+			"https://test.com/4": 8.00,
+			"https://test.com/5": 9.00,
+		}},
+	}
+	prices := map[*Product][]SuccessScrape{
+		product: {
+			// Normal discount => kept
+			{Retailer: retailer, Price: 8.00, Url: "https://test.com/1", CachedPrice: nil},
+			// Below the minimum price => rejected
+			{Retailer: retailer, Price: 0.50, Url: "https://test.com/2", CachedPrice: nil},
+			// Large drop confirmed by re-scrape => kept
+			{Retailer: retailer, Price: 3.00, Url: "https://test.com/3", CachedPrice: nil},
+			// Large drop not confirmed, but the re-scraped price is believable => kept with the new price
+			{Retailer: retailer, Price: 2.00, Url: "https://test.com/4", CachedPrice: nil},
+			// Large drop against the cached price not confirmed => rejected
+			{Retailer: retailer, Price: 4.00, Url: "https://test.com/5", CachedPrice: floatPtr(20.00)},
+		},
+	}
+
+	sanity := Sanity{MaxDrop: 0.5, MinPrice: 1.00}
+	checked, rejected := sanity.CheckPrices(context.Background(), prices)
+
+	expectedPrices := []float64{8.00, 3.00, 8.00}
+	actualScrapes := checked[product]
+	if len(actualScrapes) != len(expectedPrices) {
+		t.Fatalf("unexpected length: expected %d, got %d", len(expectedPrices), len(actualScrapes))
+	}
+	for i, price := range expectedPrices {
+		if actualScrapes[i].Price != price {
+			t.Errorf("unexpected price: expected %.2f, got %.2f", price, actualScrapes[i].Price)
+		}
+	}
+
+	expectedRejected := []string{"https://test.com/2", "https://test.com/4", "https://test.com/5"}
+	if len(rejected) != len(expectedRejected) {
+		t.Fatalf("unexpected rejected length: expected %d, got %d", len(expectedRejected), len(rejected))
+	}
+	for i, url := range expectedRejected {
+		if rejected[i].Url != url {
+			t.Errorf("unexpected rejected url: expected %s, got %s", url, rejected[i].Url)
+		}
+	}
+}
+
+func TestCheckPricesConfirmedSeller(t *testing.T) {
+	product := &Product{
+		Name:         "Test Product",
+		BasePrice:    10.00,
+		SellerPolicy: SellerPolicyAmazon,
+	}
+	retailer := &Retailer{
+		Name:        "Amazon",
+		Marketplace: true,
+		Scraper: &TestScraper{results: map[string]ScrapeResult{
+			"https://test.com/1": {Price: 3.00, Seller: "Amazon", Fulfilment: FulfilmentAmazon},
+			"https://test.com/2": {Price: 3.00, Seller: "Cheap Beauty Ltd", Fulfilment: FulfilmentThirdParty},
+		}},
+	}
+	prices := map[*Product][]SuccessScrape{
+		product: {
+			// Large drop confirmed by the same seller => kept
+			{Retailer: retailer, Price: 3.00, Url: "https://test.com/1", Seller: "Amazon", Fulfilment: FulfilmentAmazon},
+			// Large drop confirmed, but now offered by a seller outside the policy => rejected
+			{Retailer: retailer, Price: 3.00, Url: "https://test.com/2", Seller: "Amazon", Fulfilment: FulfilmentAmazon},
+		},
+	}
+
+	sanity := Sanity{MaxDrop: 0.5}
+	checked, rejected := sanity.CheckPrices(context.Background(), prices)
+
+	if len(checked[product]) != 1 || checked[product][0].Url != "https://test.com/1" {
+		t.Errorf("unexpected prices: %+v", checked[product])
+	}
+	if len(rejected) != 1 || rejected[0].Url != "https://test.com/2" || !strings.Contains(rejected[0].Reason, "Cheap Beauty Ltd") {
+		t.Errorf("unexpected rejected: %+v", rejected)
+	}
+}
+
+func TestCheckPricesCancelled(t *testing.T) {
+	product := &Product{Name: "Test Product", BasePrice: 10.00}
+	retailer := &Retailer{Name: "Test Retailer", Scraper: &TestScraper{prices: map[string]float64{"https://test.com/2": 3.00}}}
+	prices := map[*Product][]SuccessScrape{
+		product: {
+			{Retailer: retailer, Price: 8.00, Url: "https://test.com/1"},
+			{Retailer: retailer, Price: 3.00, Url: "https://test.com/2"},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Shutting down during the confirmation delay => the unconfirmed price is dropped rather than rejected
+	sanity := Sanity{MaxDrop: 0.5, ConfirmDelay: time.Hour}
+	checked, rejected := sanity.CheckPrices(ctx, prices)
+
+	if len(checked[product]) != 1 || checked[product][0].Url != "https://test.com/1" {
+		t.Errorf("unexpected prices: %+v", checked[product])
+	}
+	if len(rejected) != 0 {
+		t.Errorf("expected no rejections, got %+v", rejected)
+	}
+}
+
+func TestAddRejectedScrapes(t *testing.T) {
+	cache := newTestCache(t)
+	product := &Product{Name: "Test Product"}
+	retailer := &Retailer{Name: "Test Retailer"}
+
+	old := time.Now().Add(-2 * rejectedScrapeRetention).Unix()
+	_, err := cache.db.Exec("INSERT INTO rejected_scrapes (provider, product, label, url, price, reason, rejected_at) VALUES (?, ?, '', ?, 30, ?, ?)", retailer.Name, product.Name, "https://test.com/1", "too cheap", old)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, price := range []float64{0.50, 0.40} {
+		err := cache.AddRejectedScrapes([]RejectedScrape{{Product: product, Retailer: retailer, Price: price, Url: "https://test.com/1", Reason: "too cheap"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Every recent rejection is kept, but ones older than the retention period are removed
+	var count, price int
+	err = cache.db.QueryRow("SELECT COUNT(*), MIN(price) FROM rejected_scrapes").Scan(&count, &price)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 2 || price != 40 {
+		t.Errorf("unexpected rejected scrapes: expected 2 from 40, got %d from %d", count, price)
+	}
+}
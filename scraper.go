@@ -2,21 +2,103 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/gocolly/colly/v2"
 	"github.com/gocolly/colly/v2/extensions"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 )
 
 type Scraper interface {
-	ExtractPrice(ctx context.Context, url string) (float64, error)
+	Scrape(ctx context.Context, url string) (ScrapeResult, error)
+}
+
+type ScrapeResult struct {
+	Price float64
+	// The loyalty card price, if the page shows one separately from the standard price
+	MemberPrice  *float64
+	FinalUrl     string
+	Title        string
+	CanonicalUrl string
+	// Promotion badges shown on the page, such as "3 for 2"
+	Promotions []string
+	// Who is selling and dispatching the offer, for marketplace retailers
+	Seller     string
+	ShipsFrom  string
+	Fulfilment Fulfilment
+	OutOfStock bool
+}
+
+type ErrorClass string
+
+const (
+	ErrorClassNotFound ErrorClass = "not_found"
+	ErrorClassBlocked  ErrorClass = "blocked"
+	ErrorClassHTTP     ErrorClass = "http_error"
+	ErrorClassNetwork  ErrorClass = "network_error"
+	ErrorClassSelector ErrorClass = "selector_not_found"
+	ErrorClassParse    ErrorClass = "parse_error"
+	ErrorClassUnknown  ErrorClass = "unknown"
+)
+
+var (
+	ErrNoMatchingElement = errors.New("no matching elements found")
+	ErrPriceParse        = errors.New("failed to parse price")
+)
+
+type HTTPError struct {
+	Url        string
+	StatusCode int
+	Err        error
+}
+
+func (h *HTTPError) Error() string {
+	return fmt.Sprintf("request URL: %s failed with status %d and error: %v", h.Url, h.StatusCode, h.Err)
+}
+
+func (h *HTTPError) Unwrap() error {
+	return h.Err
+}
+
+func ClassifyError(err error) ErrorClass {
+	var httpErr *HTTPError
+	switch {
+	case errors.As(err, &httpErr):
+		switch {
+		case httpErr.StatusCode == 0:
+			return ErrorClassNetwork
+		case httpErr.StatusCode == 404 || httpErr.StatusCode == 410:
+			return ErrorClassNotFound
+		case httpErr.StatusCode == 403 || httpErr.StatusCode == 429 || httpErr.StatusCode == 503:
+			return ErrorClassBlocked
+		default:
+			return ErrorClassHTTP
+		}
+	case errors.Is(err, ErrNoMatchingElement):
+		return ErrorClassSelector
+	case errors.Is(err, ErrPriceParse):
+		return ErrorClassParse
+	default:
+		return ErrorClassUnknown
+	}
 }
 
 type baseScraper struct {
 	selector string
 	getText  func(e *colly.HTMLElement) string
+
+	memberSelector string
+	getMemberText  func(e *colly.HTMLElement) string
+
+	promotionSelector string
+
+	sellerSelector    string
+	shipsFromSelector string
+
+	outOfStockSelector string
 }
 
 func newBaseScraper(selector string, getText func(e *colly.HTMLElement) string) *baseScraper {
@@ -26,7 +108,33 @@ func newBaseScraper(selector string, getText func(e *colly.HTMLElement) string)
 	}
 }
 
-func (b *baseScraper) extractPrice(ctx context.Context, url string) (float64, error) {
+// withMemberPrice also reads a loyalty card price from the page, which is optional as not every product has one
+func (b *baseScraper) withMemberPrice(selector string, getText func(e *colly.HTMLElement) string) *baseScraper {
+	b.memberSelector = selector
+	b.getMemberText = getText
+	return b
+}
+
+// withPromotions also reads any promotion badges matching the selector from the page
+func (b *baseScraper) withPromotions(selector string) *baseScraper {
+	b.promotionSelector = selector
+	return b
+}
+
+// withSeller also reads who is selling and dispatching the offer, for marketplace retailers
+func (b *baseScraper) withSeller(sellerSelector, shipsFromSelector string) *baseScraper {
+	b.sellerSelector = sellerSelector
+	b.shipsFromSelector = shipsFromSelector
+	return b
+}
+
+// withOutOfStock marks the listing as out of stock when the selector matches an element on the page
+func (b *baseScraper) withOutOfStock(selector string) *baseScraper {
+	b.outOfStockSelector = selector
+	return b
+}
+
+func (b *baseScraper) scrape(ctx context.Context, url string) (ScrapeResult, error) {
 	c := colly.NewCollector()
 	c.Context = ctx
 
@@ -35,59 +143,116 @@ func (b *baseScraper) extractPrice(ctx context.Context, url string) (float64, er
 	var price *float64
 	var scrapeError error
 	var foundElement bool
+	var result ScrapeResult
 
 	// Set up handlers
 	c.OnHTML(b.selector, func(e *colly.HTMLElement) {
 		foundElement = true
 		scrapedPrice, err := parsePrice(b.getText(e))
 		if err != nil {
-			scrapeError = fmt.Errorf("failed to parse price: %w", err)
+			scrapeError = fmt.Errorf("%w: %v", ErrPriceParse, err)
 			return
 		}
 		price = scrapedPrice
 	})
 
+	if b.memberSelector != "" {
+		c.OnHTML(b.memberSelector, func(e *colly.HTMLElement) {
+			if memberPrice, err := parsePrice(b.getMemberText(e)); err == nil && result.MemberPrice == nil {
+				result.MemberPrice = memberPrice
+			}
+		})
+	}
+
+	if b.promotionSelector != "" {
+		c.OnHTML(b.promotionSelector, func(e *colly.HTMLElement) {
+			promotion := normalisePromotion(e.Text)
+			if promotion != "" && !slices.Contains(result.Promotions, promotion) {
+				result.Promotions = append(result.Promotions, promotion)
+			}
+		})
+	}
+
+	if b.sellerSelector != "" {
+		c.OnHTML(b.sellerSelector, func(e *colly.HTMLElement) {
+			if result.Seller == "" {
+				result.Seller = strings.TrimSpace(e.Text)
+			}
+		})
+		c.OnHTML(b.shipsFromSelector, func(e *colly.HTMLElement) {
+			if result.ShipsFrom == "" {
+				result.ShipsFrom = strings.TrimSpace(e.Text)
+			}
+		})
+	}
+
+	if b.outOfStockSelector != "" {
+		c.OnHTML(b.outOfStockSelector, func(e *colly.HTMLElement) {
+			result.OutOfStock = true
+		})
+	}
+
+	c.OnHTML("title", func(e *colly.HTMLElement) {
+		if result.Title == "" {
+			result.Title = strings.TrimSpace(e.Text)
+		}
+	})
+
+	c.OnHTML(`link[rel="canonical"]`, func(e *colly.HTMLElement) {
+		result.CanonicalUrl = e.Request.AbsoluteURL(e.Attr("href"))
+	})
+
 	c.OnScraped(func(r *colly.Response) {
+		// The request URL is updated to the final URL after any redirects
+		result.FinalUrl = r.Request.URL.String()
 		if !foundElement && scrapeError == nil {
-			scrapeError = fmt.Errorf("no matching elements found for selector %s at %s", b.selector, r.Request.URL)
+			scrapeError = fmt.Errorf("%w for selector %s at %s", ErrNoMatchingElement, b.selector, r.Request.URL)
 		}
 	})
 
 	c.OnError(func(r *colly.Response, err error) {
-		scrapeError = fmt.Errorf("request URL: %s failed with response: %v and error: %v\n", r.Request.URL, r, err)
+		scrapeError = &HTTPError{Url: r.Request.URL.String(), StatusCode: r.StatusCode, Err: err}
 	})
 
 	// Visit URL and wait for completion
 	err := c.Visit(url)
 	if err != nil {
-		return 0, fmt.Errorf("failed to visit %s: %w", url, err)
+		// Prefer the error from the handler, as it includes the response status
+		if scrapeError != nil {
+			return result, scrapeError
+		}
+		return result, fmt.Errorf("failed to visit %s: %w", url, err)
 	}
 
 	c.Wait()
 
 	// Determine result
 	if scrapeError != nil {
-		return 0, scrapeError
+		return result, scrapeError
 	}
 
 	if price == nil {
-		return 0, fmt.Errorf("no price found at %s", url)
+		return result, fmt.Errorf("no price found at %s", url)
 	}
 
-	return *price, nil
+	result.Price = *price
+	return result, nil
 }
 
 type BootsScraper struct {
 	baseScraper *baseScraper
 }
 
-func (b *BootsScraper) ExtractPrice(ctx context.Context, url string) (float64, error) {
-	return b.baseScraper.extractPrice(ctx, url)
+func (b *BootsScraper) Scrape(ctx context.Context, url string) (ScrapeResult, error) {
+	return b.baseScraper.scrape(ctx, url)
 }
 
 func NewBootsScraper() *BootsScraper {
 	return &BootsScraper{
-		baseScraper: newBaseScraper("div#PDP_productPrice", getText),
+		baseScraper: newBaseScraper("div#PDP_productPrice", getText).
+			withMemberPrice("div#PDP_productPriceAdvantageCard", getText).
+			withPromotions("div#PDP_promotions a").
+			withOutOfStock("div#PDP_outOfStock"),
 	}
 }
 
@@ -95,13 +260,18 @@ type AmazonScraper struct {
 	baseScraper *baseScraper
 }
 
-func (a *AmazonScraper) ExtractPrice(ctx context.Context, url string) (float64, error) {
-	return a.baseScraper.extractPrice(ctx, url)
+func (a *AmazonScraper) Scrape(ctx context.Context, url string) (ScrapeResult, error) {
+	result, err := a.baseScraper.scrape(ctx, url)
+	result.Fulfilment = amazonFulfilment(result.Seller, result.ShipsFrom)
+	return result, err
 }
 
 func NewAmazonScraper() *AmazonScraper {
 	return &AmazonScraper{
-		baseScraper: newBaseScraper("span#tp_price_block_total_price_ww", getText),
+		baseScraper: newBaseScraper("span#tp_price_block_total_price_ww", getText).
+			withPromotions("span.promoPriceBlockMessage").
+			withSeller("#merchantInfoFeature_feature_div .offer-display-feature-text-message", "#fulfillerInfoFeature_feature_div .offer-display-feature-text-message").
+			withOutOfStock("div#outOfStock"),
 	}
 }
 
@@ -109,15 +279,15 @@ type LookFantasticScraper struct {
 	baseScraper *baseScraper
 }
 
-func (l *LookFantasticScraper) ExtractPrice(ctx context.Context, url string) (float64, error) {
-	return l.baseScraper.extractPrice(ctx, url)
+func (l *LookFantasticScraper) Scrape(ctx context.Context, url string) (ScrapeResult, error) {
+	return l.baseScraper.scrape(ctx, url)
 }
 
 func NewLookFantasticScraper() *LookFantasticScraper {
 	return &LookFantasticScraper{
 		baseScraper: newBaseScraper("div#product-price", func(e *colly.HTMLElement) string {
 			return e.ChildText("span")
-		}),
+		}).withPromotions("div.productPromotions p").withOutOfStock("div.productAddToBasket-soldOut"),
 	}
 }
 
@@ -125,13 +295,16 @@ type SuperdrugScraper struct {
 	baseScraper *baseScraper
 }
 
-func (s *SuperdrugScraper) ExtractPrice(ctx context.Context, url string) (float64, error) {
-	return s.baseScraper.extractPrice(ctx, url)
+func (s *SuperdrugScraper) Scrape(ctx context.Context, url string) (ScrapeResult, error) {
+	return s.baseScraper.scrape(ctx, url)
 }
 
 func NewSuperdrugScraper() *SuperdrugScraper {
 	return &SuperdrugScraper{
-		baseScraper: newBaseScraper("span.price__current", getText),
+		baseScraper: newBaseScraper("span.price__current", getText).
+			withMemberPrice("span.price__card", getText).
+			withPromotions("div.promotion-badge").
+			withOutOfStock("div.out-of-stock"),
 	}
 }
 
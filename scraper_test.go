@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestScrapeMemberPrice(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><title>Test Product</title></head><body>
+			<div id="PDP_productPrice">£10.00</div>
+			<div id="PDP_productPriceAdvantageCard">£8.00 with Advantage Card</div>
+		</body></html>`)
+	}))
+	defer server.Close()
+
+	result, err := NewBootsScraper().Scrape(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Price != 10.00 {
+		t.Errorf("unexpected price: expected 10.00, got %.2f", result.Price)
+	}
+	if result.MemberPrice == nil || *result.MemberPrice != 8.00 {
+		t.Fatalf("unexpected member price: expected 8.00, got %v", result.MemberPrice)
+	}
+
+	// The member price only applies when we hold the card
+	retailer := &Retailer{Name: "Boots"}
+	if price, priceType := retailer.PriceFor(result); price != 10.00 || priceType != PriceTypeStandard {
+		t.Errorf("unexpected price without the card: got %.2f (%s)", price, priceType)
+	}
+
+	retailer.Member = true
+	if price, priceType := retailer.PriceFor(result); price != 8.00 || priceType != PriceTypeMember {
+		t.Errorf("unexpected price with the card: got %.2f (%s)", price, priceType)
+	}
+}
+
+func TestScrapeAmazonSeller(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body>
+			<span id="tp_price_block_total_price_ww">£12.99</span>
+			<div id="fulfillerInfoFeature_feature_div"><span class="offer-display-feature-text-message">Amazon</span></div>
+			<div id="merchantInfoFeature_feature_div"><span class="offer-display-feature-text-message">Beauty Deals Ltd</span></div>
+		</body></html>`)
+	}))
+	defer server.Close()
+
+	result, err := NewAmazonScraper().Scrape(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Seller != "Beauty Deals Ltd" {
+		t.Errorf("unexpected seller: expected Beauty Deals Ltd, got %s", result.Seller)
+	}
+	if result.Fulfilment != FulfilmentByAmazon {
+		t.Errorf("unexpected fulfilment: expected %s, got %s", FulfilmentByAmazon, result.Fulfilment)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Fulfilment string
+
+const (
+	FulfilmentUnknown    Fulfilment = ""
+	FulfilmentAmazon     Fulfilment = "sold_by_amazon"
+	FulfilmentByAmazon   Fulfilment = "fulfilled_by_amazon"
+	FulfilmentThirdParty Fulfilment = "third_party"
+)
+
+// SellerPolicy decides which offers on a marketplace retailer are accepted for a product
+type SellerPolicy string
+
+const (
+	SellerPolicyAny               SellerPolicy = "any"
+	SellerPolicyFulfilledByAmazon SellerPolicy = "fulfilled_by_amazon"
+	SellerPolicyAmazon            SellerPolicy = "amazon"
+)
+
+func parseSellerPolicy(policy string) (SellerPolicy, error) {
+	switch SellerPolicy(policy) {
+	case "", SellerPolicyAny:
+		return SellerPolicyAny, nil
+	case SellerPolicyFulfilledByAmazon, SellerPolicyAmazon:
+		return SellerPolicy(policy), nil
+	default:
+		return "", fmt.Errorf("unknown seller policy %q, expected one of any, fulfilled_by_amazon or amazon", policy)
+	}
+}
+
+func (s SellerPolicy) Accepts(fulfilment Fulfilment) bool {
+	switch s {
+	case SellerPolicyAmazon:
+		return fulfilment == FulfilmentAmazon
+	case SellerPolicyFulfilledByAmazon:
+		return fulfilment == FulfilmentAmazon || fulfilment == FulfilmentByAmazon
+	default:
+		return true
+	}
+}
+
+// amazonFulfilment works out who is selling and dispatching an Amazon offer from the "Sold by" and "Dispatches from"
+// text on its page
+func amazonFulfilment(seller, shipsFrom string) Fulfilment {
+	switch {
+	case strings.Contains(strings.ToLower(seller), "amazon"):
+		return FulfilmentAmazon
+	case strings.Contains(strings.ToLower(shipsFrom), "amazon"):
+		return FulfilmentByAmazon
+	case seller != "":
+		return FulfilmentThirdParty
+	default:
+		return FulfilmentUnknown
+	}
+}
+
+// CheckSellers rejects marketplace offers that don't meet their product's seller policy, including those where the
+// seller couldn't be found
+func CheckSellers(prices map[*Product][]SuccessScrape) (map[*Product][]SuccessScrape, []RejectedScrape) {
+	accepted := make(map[*Product][]SuccessScrape)
+	var rejected []RejectedScrape
+
+	for product, scrapes := range prices {
+		for _, scrape := range scrapes {
+			if reason := checkSeller(product, scrape); reason != "" {
+				rejected = append(rejected, newRejectedScrape(product, scrape, reason))
+				continue
+			}
+
+			accepted[product] = append(accepted[product], scrape)
+		}
+	}
+
+	return accepted, rejected
+}
+
+// checkSeller returns a rejection reason if the offer doesn't meet the product's seller policy
+func checkSeller(product *Product, scrape SuccessScrape) string {
+	if !scrape.Retailer.Marketplace || product.SellerPolicy.Accepts(scrape.Fulfilment) {
+		return ""
+	}
+
+	seller := scrape.Seller
+	if seller == "" {
+		seller = "an unknown seller"
+	}
+	return fmt.Sprintf("offer from %s (%s) does not meet the %s seller policy", seller, scrape.Fulfilment, product.SellerPolicy)
+}
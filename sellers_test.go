@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCheckSellers(t *testing.T) {
+	product := &Product{Name: "Test Product", SellerPolicy: SellerPolicyFulfilledByAmazon}
+	amazon := &Retailer{Name: "Amazon", Marketplace: true}
+	retailer := &Retailer{Name: "Test Retailer"}
+	prices := map[*Product][]SuccessScrape{
+		product: {
+			// Sold by Amazon => kept
+			{Retailer: amazon, Price: 10.00, Url: "https://test.com/1", Seller: "Amazon", Fulfilment: FulfilmentAmazon},
+			// Third party seller using Amazon's fulfilment => kept
+			{Retailer: amazon, Price: 9.00, Url: "https://test.com/2", Seller: "Beauty Deals Ltd", Fulfilment: FulfilmentByAmazon},
+			// Third party seller dispatching themselves => rejected
+			{Retailer: amazon, Price: 5.00, Url: "https://test.com/3", Seller: "Grey Imports", Fulfilment: FulfilmentThirdParty},
+			// Seller couldn't be found => rejected
+			{Retailer: amazon, Price: 6.00, Url: "https://test.com/4"},
+			// Not a marketplace => kept
+			{Retailer: retailer, Price: 8.00, Url: "https://test.com/5"},
+		},
+	}
+
+	accepted, rejected := CheckSellers(prices)
+
+	expectedAccepted := []string{"https://test.com/1", "https://test.com/2", "https://test.com/5"}
+	if len(accepted[product]) != len(expectedAccepted) {
+		t.Fatalf("unexpected accepted length: expected %d, got %d", len(expectedAccepted), len(accepted[product]))
+	}
+	for i, url := range expectedAccepted {
+		if accepted[product][i].Url != url {
+			t.Errorf("unexpected accepted url: expected %s, got %s", url, accepted[product][i].Url)
+		}
+	}
+
+	expectedRejected := []string{"https://test.com/3", "https://test.com/4"}
+	if len(rejected) != len(expectedRejected) {
+		t.Fatalf("unexpected rejected length: expected %d, got %d", len(expectedRejected), len(rejected))
+	}
+	for i, url := range expectedRejected {
+		if rejected[i].Url != url {
+			t.Errorf("unexpected rejected url: expected %s, got %s", url, rejected[i].Url)
+		}
+	}
+}
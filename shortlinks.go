@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var defaultShortLinkHosts = []string{"amzn.to", "amzn.eu", "a.co", "bit.ly", "tinyurl.com", "t.co"}
+
+// LinkResolver expands shortened links to the product page they redirect to, storing the result so the redirect
+// only needs following once
+type LinkResolver struct {
+	client     *http.Client
+	cache      *Cache
+	shortHosts map[string]bool
+}
+
+func NewLinkResolver(cache *Cache, shortHosts []string) *LinkResolver {
+	hosts := make(map[string]bool)
+	for _, host := range append(defaultShortLinkHosts, shortHosts...) {
+		hosts[strings.ToLower(host)] = true
+	}
+
+	return &LinkResolver{
+		client:     &http.Client{Timeout: 30 * time.Second},
+		cache:      cache,
+		shortHosts: hosts,
+	}
+}
+
+func (l *LinkResolver) IsShortLink(link string) bool {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return l.shortHosts[strings.ToLower(parsed.Hostname())]
+}
+
+func (l *LinkResolver) Resolve(ctx context.Context, link string) (string, error) {
+	if !l.IsShortLink(link) {
+		return link, nil
+	}
+
+	resolved, err := l.cache.GetShortLink(link)
+	if err != nil {
+		return "", err
+	}
+	if resolved != "" {
+		return resolved, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to follow %s: %w", link, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	resolved = resp.Request.URL.String()
+	if resolved == link {
+		return "", fmt.Errorf("%s did not redirect to a product page", link)
+	}
+
+	return resolved, l.cache.SetShortLink(link, resolved)
+}
+
+// ResolveShortLinks replaces any shortened links with the product page they point to
+func (p Products) ResolveShortLinks(ctx context.Context, resolver *LinkResolver) error {
+	var errs []error
+
+	for _, product := range p {
+		for _, link := range product.Links {
+			resolved, err := resolver.Resolve(ctx, link.Url)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("error resolving %s link for %s: %w", listingName(link.Retailer, link.Label), product.Name, err))
+				continue
+			}
+
+			if resolved != link.Url {
+				link.Url = CanonicalUrl(resolved)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResolveShortLinks(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/short":
+			requests++
+			http.Redirect(w, r, "/product/123?utm_source=share", http.StatusMovedPermanently)
+		case "/product/123":
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cache := newTestCache(t)
+	resolver := NewLinkResolver(cache, []string{"127.0.0.1"})
+	retailer := &Retailer{Name: "Test Retailer"}
+	// The test server is plain http, but the expanded link is normalised like any other
+	expected := "https://" + strings.TrimPrefix(server.URL, "http://") + "/product/123"
+
+	// The second run should use the stored link rather than following the redirect again
+	for run := 1; run <= 2; run++ {
+		products := Products{{Name: "Test Product", Links: []*Link{{Retailer: retailer, Url: server.URL + "/short"}}}}
+
+		err := products.ResolveShortLinks(context.Background(), resolver)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if actual := products[0].Links[0].Url; actual != expected {
+			t.Errorf("run %d: unexpected link: expected %s, got %s", run, expected, actual)
+		}
+	}
+
+	if requests != 1 {
+		t.Errorf("unexpected requests to the short link: expected 1, got %d", requests)
+	}
+}
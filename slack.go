@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits on Slack messages, counted in characters
+const (
+	slackMaxBlocks     = 50
+	slackMaxHeaderText = 150
+	slackMaxText       = 3000
+)
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+type slackMessage struct {
+	// Shown in notifications and by clients that can't show blocks
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type string     `json:"type"`
+	Text *slackText `json:"text,omitempty"`
+}
+
+type slackText struct {
+	Type  string `json:"type"`
+	Text  string `json:"text"`
+	Emoji bool   `json:"emoji,omitempty"`
+}
+
+// SlackClient posts notifications to a Slack incoming webhook using Block Kit, with a section for each product
+type SlackClient struct {
+	client *http.Client
+	url    string
+}
+
+func NewSlackClient(config ChatWebhook) (*SlackClient, error) {
+	if config.Url == "" {
+		return nil, fmt.Errorf("slack webhook url is required")
+	}
+
+	return &SlackClient{
+		client: &http.Client{Timeout: 30 * time.Second},
+		url:    config.Url,
+	}, nil
+}
+
+func (s *SlackClient) Notify(ctx context.Context, notification Notification) error {
+	for _, message := range newSlackMessages(notification) {
+		body, err := json.Marshal(message)
+		if err != nil {
+			return fmt.Errorf("error encoding message: %v", err)
+		}
+
+		err = postJSON(ctx, s.client, s.url, body, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SlackClient) Stop() error {
+	return nil
+}
+
+// newSlackMessages builds the blocks for a notification, split into as many messages as Slack's block limit needs
+func newSlackMessages(notification Notification) []slackMessage {
+	title := fmt.Sprintf("%s %s", notification.Icon, notification.Title)
+	blocks := []slackBlock{{
+		Type: "header",
+		Text: &slackText{Type: "plain_text", Text: truncateText(title, slackMaxHeaderText), Emoji: true},
+	}}
+
+	for _, category := range notification.Categories {
+		blocks = append(blocks, slackBlock{Type: "divider"})
+		blocks = append(blocks, slackSections([]string{"*" + slackEscaper.Replace(category.Name) + "*"})...)
+
+		for _, deal := range category.Deals {
+			lines := []string{
+				"*" + slackEscaper.Replace(deal.Product) + "*",
+				fmt.Sprintf("Base price: £%.2f", deal.BasePrice),
+			}
+			for i, price := range deal.Prices {
+				lines = append(lines, slackPrice(price, i == 0))
+			}
+
+			blocks = append(blocks, slackSections(lines)...)
+		}
+	}
+
+	for _, section := range notification.Sections {
+		var lines []string
+		if section.Title != "" {
+			title := "*" + slackEscaper.Replace(section.Title) + "*"
+			if section.Icon != "" {
+				title = section.Icon + " " + title
+			}
+			lines = append(lines, title)
+		}
+
+		for _, item := range section.Items {
+			line := "• *" + slackEscaper.Replace(item.Name) + "*"
+			if item.Listing != "" {
+				line += fmt.Sprintf(" at <%s|%s>", item.Url, slackEscaper.Replace(item.Listing))
+			}
+			if item.Detail != "" {
+				line += section.detailSeparator() + slackEscaper.Replace(item.Detail)
+			}
+			lines = append(lines, line)
+		}
+
+		if section.Footer != "" {
+			lines = append(lines, slackEscaper.Replace(section.Footer))
+		}
+
+		blocks = append(blocks, slackSections(lines)...)
+	}
+
+	if notification.Summary != "" {
+		blocks = append(blocks, slackSections([]string{"*" + slackEscaper.Replace(notification.Summary) + "*"})...)
+	}
+
+	var messages []slackMessage
+	for start := 0; start < len(blocks); start += slackMaxBlocks {
+		end := min(start+slackMaxBlocks, len(blocks))
+		messages = append(messages, slackMessage{Text: title, Blocks: blocks[start:end]})
+	}
+
+	return messages
+}
+
+func slackPrice(price DealPrice, best bool) string {
+	amount := fmt.Sprintf("£%.2f", price.Price)
+	if best {
+		amount = "*" + amount + "*"
+	}
+
+	return fmt.Sprintf("%s%s%s at <%s|%s> (%s)", priceChangeMarker(price.Change), amount,
+		slackEscaper.Replace(priceExtras(price)), price.Url, slackEscaper.Replace(price.Listing), discountText(price))
+}
+
+// slackSections packs lines into as few mrkdwn sections as fit within Slack's text limit
+func slackSections(lines []string) []slackBlock {
+	var blocks []slackBlock
+	var text strings.Builder
+
+	flush := func() {
+		if text.Len() > 0 {
+			blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: text.String()}})
+			text.Reset()
+		}
+	}
+
+	for _, line := range lines {
+		line = truncateText(line, slackMaxText)
+		if text.Len() > 0 && utf8.RuneCountInString(text.String())+1+utf8.RuneCountInString(line) > slackMaxText {
+			flush()
+		}
+
+		if text.Len() > 0 {
+			text.WriteString("\n")
+		}
+		text.WriteString(line)
+	}
+	flush()
+
+	return blocks
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSlackClient(t *testing.T) {
+	var messages []slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message slackMessage
+		err := json.NewDecoder(r.Body).Decode(&message)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		messages = append(messages, message)
+	}))
+	defer server.Close()
+
+	client, err := NewSlackClient(ChatWebhook{Url: server.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	retailer := &Retailer{Name: "Boots & Co"}
+	prices := map[*Product][]SuccessScrape{
+		&Product{Name: "Serum <Travel>", BasePrice: 10.00, Category: "skincare"}: {
+			{Retailer: retailer, Price: 7.00, Url: "https://test.com/1", CachedPrice: floatPtr(8.00)},
+		},
+	}
+
+	err = notify(context.Background(), prices, client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(messages))
+	}
+	message := messages[0]
+	if message.Text != "🛍️ Cheaper prices found" || len(message.Blocks) != 4 {
+		t.Fatalf("unexpected message: %+v", message)
+	}
+	if message.Blocks[0].Type != "header" || message.Blocks[0].Text.Text != "🛍️ Cheaper prices found" {
+		t.Errorf("unexpected header: %+v", message.Blocks[0])
+	}
+
+	expected := "*Serum &lt;Travel&gt;*\nBase price: £10.00\n*£7.00* at <https://test.com/1|Boots &amp; Co> (-£3.00 | 30.00% off)"
+	if text := message.Blocks[3].Text.Text; text != expected {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+}
+
+func TestSlackMessagesSplit(t *testing.T) {
+	var deals []Deal
+	for i := 0; i < 60; i++ {
+		deals = append(deals, Deal{
+			Product:   fmt.Sprintf("Product %d", i),
+			BasePrice: 10.00,
+			Prices:    []DealPrice{{Listing: "Test Retailer", Url: "https://test.com", Price: 7.00}},
+		})
+	}
+
+	messages := newSlackMessages(Notification{Kind: NotificationDeals, Title: "Deals", Categories: []DealCategory{{Name: "Other", Deals: deals}}})
+	if len(messages) != 2 || len(messages[0].Blocks) != slackMaxBlocks || len(messages[1].Blocks) != 13 {
+		t.Fatalf("unexpected messages: %d", len(messages))
+	}
+
+	var lines []string
+	for i := 0; i < 100; i++ {
+		lines = append(lines, fmt.Sprintf("%050d", i))
+	}
+	blocks := slackSections(lines)
+	if len(blocks) != 2 {
+		t.Fatalf("expected the lines to be split over 2 sections, got %d", len(blocks))
+	}
+	for _, block := range blocks {
+		if len(block.Text.Text) > slackMaxText {
+			t.Errorf("section is over the limit with %d characters", len(block.Text.Text))
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	defaultTelegramApiUrl = "https://api.telegram.org"
+	// Telegram counts message length in UTF-16 code units
+	telegramMaxMessage = 4096
+	telegramMaxButtons = 100
+	// Names are cut to this length before being wrapped in markup, so a long one can't push its block over the limit
+	telegramMaxName = 256
+)
+
+var telegramTag = regexp.MustCompile(`<[^>]*>`)
+
+type telegramMessage struct {
+	ChatID             string                     `json:"chat_id"`
+	Text               string                     `json:"text"`
+	ParseMode          string                     `json:"parse_mode"`
+	LinkPreviewOptions telegramLinkPreviewOptions `json:"link_preview_options"`
+	ReplyMarkup        *telegramReplyMarkup       `json:"reply_markup,omitempty"`
+}
+
+type telegramLinkPreviewOptions struct {
+	IsDisabled bool `json:"is_disabled"`
+}
+
+type telegramReplyMarkup struct {
+	InlineKeyboard [][]telegramButton `json:"inline_keyboard"`
+}
+
+type telegramButton struct {
+	Text string `json:"text"`
+	Url  string `json:"url"`
+}
+
+// telegramBlock is a part of a message that is kept together when the message is split
+type telegramBlock struct {
+	text   string
+	button *telegramButton
+}
+
+// TelegramClient sends notifications through a Telegram bot, with a button to open each deal at its retailer
+type TelegramClient struct {
+	client *http.Client
+	url    string
+	chatID string
+}
+
+func NewTelegramClient(config Telegram) (*TelegramClient, error) {
+	if config.Token == "" {
+		return nil, fmt.Errorf("telegram bot token is required")
+	}
+	if config.ChatID == "" {
+		return nil, fmt.Errorf("telegram chat_id is required")
+	}
+
+	apiUrl := config.ApiUrl
+	if apiUrl == "" {
+		apiUrl = defaultTelegramApiUrl
+	}
+
+	return &TelegramClient{
+		client: &http.Client{Timeout: 30 * time.Second},
+		url:    fmt.Sprintf("%s/bot%s/sendMessage", strings.TrimSuffix(apiUrl, "/"), config.Token),
+		chatID: config.ChatID,
+	}, nil
+}
+
+func (t *TelegramClient) Notify(ctx context.Context, notification Notification) error {
+	for _, message := range newTelegramMessages(t.chatID, notification) {
+		body, err := json.Marshal(message)
+		if err != nil {
+			return fmt.Errorf("error encoding message: %v", err)
+		}
+
+		err = postJSON(ctx, t.client, t.url, body, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *TelegramClient) Stop() error {
+	return nil
+}
+
+// newTelegramMessages renders a notification as HTML, split into as many messages as Telegram's length limit needs
+func newTelegramMessages(chatID string, notification Notification) []telegramMessage {
+	var messages []telegramMessage
+	var text strings.Builder
+	var buttons [][]telegramButton
+
+	flush := func() {
+		if text.Len() == 0 {
+			return
+		}
+
+		message := telegramMessage{
+			ChatID:             chatID,
+			Text:               strings.TrimSpace(text.String()),
+			ParseMode:          "HTML",
+			LinkPreviewOptions: telegramLinkPreviewOptions{IsDisabled: true},
+		}
+		if len(buttons) > 0 {
+			message.ReplyMarkup = &telegramReplyMarkup{InlineKeyboard: buttons}
+		}
+
+		messages = append(messages, message)
+		text.Reset()
+		buttons = nil
+	}
+
+	for _, block := range telegramBlocks(notification) {
+		if telegramLength(text.String()+block.text) > telegramMaxMessage || (block.button != nil && len(buttons) == telegramMaxButtons) {
+			flush()
+		}
+
+		text.WriteString(block.text)
+		if block.button != nil {
+			buttons = append(buttons, []telegramButton{*block.button})
+		}
+	}
+	flush()
+
+	return messages
+}
+
+func telegramBlocks(notification Notification) []telegramBlock {
+	var blocks []telegramBlock
+	add := func(text string, button *telegramButton) {
+		// A block still over the limit on its own is sent as plain text cut short, as cutting its markup could leave a
+		// tag open
+		if telegramLength(text) > telegramMaxMessage {
+			text = telegramEscape(html.UnescapeString(telegramTag.ReplaceAllString(text, "")), telegramMaxMessage)
+		}
+		blocks = append(blocks, telegramBlock{text: text, button: button})
+	}
+
+	header := fmt.Sprintf("%s <b>%s</b>", notification.Icon, telegramEscape(notification.Title, telegramMaxName))
+	if notification.Kind == NotificationDeals {
+		header += " 🤑"
+	}
+	add(header+"\n\n", nil)
+
+	for _, category := range notification.Categories {
+		add(fmt.Sprintf("<b>%s</b>\n\n", telegramEscape(category.Name, telegramMaxName)), nil)
+
+		for _, deal := range category.Deals {
+			var text strings.Builder
+			fmt.Fprintf(&text, "<b>%s</b>\n", telegramEscape(deal.Product, telegramMaxName))
+			fmt.Fprintf(&text, "Base price: £%.2f\n", deal.BasePrice)
+			fmt.Fprintf(&text, "Best price: %s\n", telegramPrice(deal.Prices[0], true))
+
+			if len(deal.Prices) > 1 {
+				text.WriteString("Other prices:\n")
+				for _, price := range deal.Prices[1:] {
+					fmt.Fprintf(&text, "- %s\n", telegramPrice(price, false))
+				}
+			}
+			text.WriteString("\n")
+
+			best := deal.Prices[0]
+			add(text.String(), &telegramButton{Text: fmt.Sprintf("🛒 %s at %s", deal.Product, best.Listing), Url: best.Url})
+		}
+	}
+
+	for _, section := range notification.Sections {
+		if section.Title != "" {
+			title := "<b>" + telegramEscape(section.Title, telegramMaxName) + "</b>"
+			if section.Icon != "" {
+				title = section.Icon + " " + title
+			}
+			add(title+"\n\n", nil)
+		}
+
+		for _, item := range section.Items {
+			line := "- <b>" + telegramEscape(item.Name, telegramMaxName) + "</b>"
+			if item.Listing != "" {
+				line += " at " + telegramLink(item.Listing, item.Url)
+			}
+			if item.Detail != "" {
+				line += section.detailSeparator() + html.EscapeString(item.Detail)
+			}
+			add(line+"\n", nil)
+		}
+
+		if section.Footer != "" {
+			add(html.EscapeString(section.Footer)+"\n", nil)
+		}
+		add("\n", nil)
+	}
+
+	if notification.Summary != "" {
+		add("<b>"+html.EscapeString(notification.Summary)+"</b>\n", nil)
+	}
+
+	return blocks
+}
+
+func telegramPrice(price DealPrice, bold bool) string {
+	amount := fmt.Sprintf("£%.2f", price.Price)
+	if bold {
+		amount = "<b>" + amount + "</b>"
+	}
+
+	return fmt.Sprintf("%s%s%s at %s (%s)", priceChangeMarker(price.Change), amount,
+		html.EscapeString(priceExtras(price)), telegramLink(price.Listing, price.Url), html.EscapeString(discountText(price)))
+}
+
+func telegramLink(text, url string) string {
+	return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(url), telegramEscape(text, telegramMaxName))
+}
+
+// telegramEscape escapes text for HTML, cutting it short so the escaped text fits within the limit
+func telegramEscape(text string, limit int) string {
+	escaped := html.EscapeString(text)
+	if telegramLength(escaped) <= limit {
+		return escaped
+	}
+
+	var cut strings.Builder
+	length := 0
+	for _, r := range text {
+		escapedRune := html.EscapeString(string(r))
+		length += telegramLength(escapedRune)
+		if length > limit-1 {
+			break
+		}
+		cut.WriteString(escapedRune)
+	}
+
+	return cut.String() + "…"
+}
+
+func telegramLength(text string) int {
+	return len(utf16.Encode([]rune(text)))
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTelegramClient(t *testing.T) {
+	var messages []telegramMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bottoken/sendMessage" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+
+		var message telegramMessage
+		err := json.NewDecoder(r.Body).Decode(&message)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		messages = append(messages, message)
+	}))
+	defer server.Close()
+
+	client, err := NewTelegramClient(Telegram{Token: "token", ChatID: "-100123", ApiUrl: server.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	retailer := &Retailer{Name: "Test Retailer"}
+	otherRetailer := &Retailer{Name: "Other Retailer"}
+	prices := map[*Product][]SuccessScrape{
+		&Product{Name: "Serum <Travel> & Co", BasePrice: 10.00, Category: "skincare"}: {
+			{Retailer: retailer, Price: 7.00, Url: "https://test.com/1?a=1&b=2", CachedPrice: floatPtr(8.00)},
+			{Retailer: otherRetailer, Price: 8.00, Url: "https://other.com/1"},
+		},
+	}
+
+	err = notify(context.Background(), prices, client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(messages))
+	}
+	message := messages[0]
+	if message.ChatID != "-100123" || message.ParseMode != "HTML" {
+		t.Errorf("unexpected message: %+v", message)
+	}
+
+	expected := "🛍️ <b>Cheaper prices found</b> 🤑\n\n" +
+		"<b>skincare</b>\n\n" +
+		"<b>Serum &lt;Travel&gt; &amp; Co</b>\n" +
+		"Base price: £10.00\n" +
+		"Best price: <b>£7.00</b> at <a href=\"https://test.com/1?a=1&amp;b=2\">Test Retailer</a> (-£3.00 | 30.00% off)\n" +
+		"Other prices:\n" +
+		"- 🆕 £8.00 at <a href=\"https://other.com/1\">Other Retailer</a> (-£2.00 | 20.00% off)"
+	if message.Text != expected {
+		t.Errorf("expected %q, got %q", expected, message.Text)
+	}
+
+	if message.ReplyMarkup == nil || len(message.ReplyMarkup.InlineKeyboard) != 1 {
+		t.Fatalf("expected a button, got %+v", message.ReplyMarkup)
+	}
+	button := message.ReplyMarkup.InlineKeyboard[0][0]
+	if button.Text != "🛒 Serum <Travel> & Co at Test Retailer" || button.Url != "https://test.com/1?a=1&b=2" {
+		t.Errorf("unexpected button: %+v", button)
+	}
+}
+
+func TestTelegramMessagesSplit(t *testing.T) {
+	var deals []Deal
+	for i := 0; i < 40; i++ {
+		deals = append(deals, Deal{
+			Product:   fmt.Sprintf("Product %d %s", i, strings.Repeat("x", 100)),
+			BasePrice: 10.00,
+			Prices:    []DealPrice{{Listing: "Test Retailer", Url: fmt.Sprintf("https://test.com/%d", i), Price: 7.00}},
+		})
+	}
+
+	messages := newTelegramMessages("123", Notification{Kind: NotificationDeals, Title: "Deals", Categories: []DealCategory{{Name: "Other", Deals: deals}}})
+	if len(messages) < 2 {
+		t.Fatalf("expected the deals to be split, got %d messages", len(messages))
+	}
+
+	buttons := 0
+	for _, message := range messages {
+		if length := telegramLength(message.Text); length > telegramMaxMessage {
+			t.Errorf("message is over the limit with %d characters", length)
+		}
+		if strings.Count(message.Text, "<b>Product ") != len(message.ReplyMarkup.InlineKeyboard) {
+			t.Errorf("expected a button for each deal in the message: %+v", message)
+		}
+		buttons += len(message.ReplyMarkup.InlineKeyboard)
+	}
+
+	if buttons != len(deals) {
+		t.Errorf("expected %d buttons, got %d", len(deals), buttons)
+	}
+}
+
+func TestTelegramMessagesTruncated(t *testing.T) {
+	// Each & is escaped to &amp;, so the name is far over the limit once escaped
+	name := strings.Repeat("&", telegramMaxMessage)
+	var prices []DealPrice
+	for i := 0; i < 30; i++ {
+		prices = append(prices, DealPrice{Listing: fmt.Sprintf("Retailer %d %s", i, strings.Repeat("<", 100)), Url: fmt.Sprintf("https://test.com/%d", i), Price: 7.00})
+	}
+
+	notifications := []Notification{
+		{Kind: NotificationDeals, Title: "Deals", Categories: []DealCategory{{Name: "Other", Deals: []Deal{{Product: name, BasePrice: 10.00, Prices: prices[:1]}}}}},
+		{Kind: NotificationPromotions, Title: "Promotions", Sections: []Section{{Title: name, Items: []Item{{Name: name, Listing: name, Url: "https://test.com/1"}}}}},
+		// A deal with too many prices to fit in a message, even with its names cut short
+		{Kind: NotificationDeals, Title: "Deals", Categories: []DealCategory{{Name: "Other", Deals: []Deal{{Product: "Test Product", BasePrice: 10.00, Prices: prices}}}}},
+	}
+
+	for i, notification := range notifications {
+		for _, message := range newTelegramMessages("123", notification) {
+			if length := telegramLength(message.Text); length > telegramMaxMessage {
+				t.Errorf("notification %d: message is over the limit with %d characters", i, length)
+			}
+
+			// Names are cut before they're wrapped in markup, so every tag and entity is left whole
+			text := telegramTag.ReplaceAllString(message.Text, "")
+			if strings.ContainsAny(text, "<>") || strings.Count(message.Text, "<b>") != strings.Count(message.Text, "</b>") ||
+				strings.Count(message.Text, "<a ") != strings.Count(message.Text, "</a>") {
+				t.Errorf("notification %d: message has broken markup: %s", i, message.Text)
+			}
+			if strings.Count(text, "&") != strings.Count(text, "&amp;")+strings.Count(text, "&lt;")+strings.Count(text, "&gt;") {
+				t.Errorf("notification %d: message has a broken entity: %s", i, message.Text)
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+const defaultMessageTemplate = `{{.Icon}} **{{.Title}}** 🤑
+
+{{range .Categories}}**{{.Name}}**
+
+{{range .Deals}}{{template "product" .}}
+{{end}}{{end}}`
+
+const defaultProductTemplate = `**{{.Product}}**
+Base price: £{{printf "%.2f" .BasePrice}}
+Best price: {{template "price" (line (index .Prices 0) true)}}
+{{with slice .Prices 1}}Other prices:
+{{range .}}- {{template "price" (line . false)}}
+{{end}}{{end}}`
+
+const defaultPriceTemplate = `{{marker .Change}}{{if .Best}}**£{{printf "%.2f" .Price}}**{{else}}£{{printf "%.2f" .Price}}{{end}}{{extras .DealPrice}} at [{{.Listing}}]({{.Url}}) (-£{{printf "%.2f" .Discount}}{{with .DiscountSize}} per {{.}}{{end}} | {{printf "%.2f" .DiscountPct}}% off)`
+
+var templateFuncs = template.FuncMap{
+	"marker": priceChangeMarker,
+	"extras": priceExtras,
+	"line": func(price DealPrice, best bool) PriceLine {
+		return PriceLine{DealPrice: price, Best: best}
+	},
+}
+
+// PriceLine is the data for the price template, which is used for both the best price and the others
+type PriceLine struct {
+	DealPrice
+	Best bool
+}
+
+// Templates render the deals message in markdown, from a template for the whole message, each product and each
+// price
+type Templates struct {
+	deals *template.Template
+}
+
+var DefaultTemplates = func() *Templates {
+	templates, err := NewTemplates(TemplatesTOML{})
+	if err != nil {
+		panic(err)
+	}
+	return templates
+}()
+
+func NewTemplates(config TemplatesTOML) (*Templates, error) {
+	deals := template.New("deals").Funcs(templateFuncs)
+	for _, t := range []struct{ name, source, fallback string }{
+		{name: "message", source: config.Message, fallback: defaultMessageTemplate},
+		{name: "product", source: config.Product, fallback: defaultProductTemplate},
+		// The price is a single line, so the newline before the closing quotes of a multi-line string is dropped
+		{name: "price", source: strings.TrimSuffix(config.Price, "\n"), fallback: defaultPriceTemplate},
+	} {
+		source := t.source
+		if source == "" {
+			source = t.fallback
+		}
+
+		_, err := deals.New(t.name).Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s template: %v", t.name, err)
+		}
+	}
+
+	templates := &Templates{deals: deals}
+
+	// Rendering an example catches mistakes such as unknown fields when the config is loaded, rather than at the
+	// first deal
+	err := deals.ExecuteTemplate(io.Discard, "message", exampleDealsNotification())
+	if err != nil {
+		return nil, fmt.Errorf("error rendering deals template: %v", err)
+	}
+
+	return templates, nil
+}
+
+func exampleDealsNotification() Notification {
+	cachedPrice, unitPrice, effectivePrice, standardPrice := 8.50, 15.00, 10.49, 9.00
+
+	return Notification{
+		Kind:  NotificationDeals,
+		Icon:  "🛍️",
+		Title: "Cheaper prices found",
+		Categories: []DealCategory{{
+			Name: "skincare",
+			Deals: []Deal{{
+				Product:   "INKEY List Q10 Serum",
+				Category:  "skincare",
+				BasePrice: 9.00,
+				Prices: []DealPrice{
+					{
+						Retailer:      "Boots",
+						Listing:       "Boots",
+						Url:           "https://www.boots.com/",
+						Price:         7.50,
+						CachedPrice:   &cachedPrice,
+						Change:        PriceChangeDown,
+						Discount:      1.50,
+						DiscountPct:   16.67,
+						UnitPrice:     &unitPrice,
+						UnitQuantity:  "100ml",
+						StandardPrice: &standardPrice,
+					},
+					{
+						Retailer:       "Look Fantastic",
+						Label:          "50ml",
+						Listing:        "Look Fantastic (50ml)",
+						Url:            "https://www.lookfantastic.com/",
+						Price:          7.99,
+						Change:         PriceChangeNew,
+						Discount:       4.21,
+						DiscountPct:    46.73,
+						DiscountSize:   "30ml",
+						EffectivePrice: &effectivePrice,
+					},
+				},
+			}},
+		}},
+	}
+}
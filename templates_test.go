@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplates(t *testing.T) {
+	templates, err := NewTemplates(TemplatesTOML{
+		Message: "Deals\n{{range .Categories}}{{range .Deals}}{{template \"product\" .}}{{end}}{{end}}",
+		Product: "{{.Product}}:\n{{range $i, $price := .Prices}}{{template \"price\" (line $price (eq $i 0))}}\n{{end}}",
+		Price:   "{{if .Best}}BEST {{end}}{{.Listing}} £{{printf \"%.2f\" .Price}}{{if eq .Change \"new\"}} NEW{{end}}\n",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	message, err := templates.RenderMarkdown(exampleDealsNotification())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "Deals\nINKEY List Q10 Serum:\nBEST Boots £7.50\nLook Fantastic (50ml) £7.99 NEW\n"
+	if message != expected {
+		t.Errorf("expected %q, got %q", expected, message)
+	}
+}
+
+func TestTemplatesDefaults(t *testing.T) {
+	templates, err := NewTemplates(TemplatesTOML{Price: "{{.Listing}}"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	message, err := templates.RenderMarkdown(exampleDealsNotification())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(message, "🛍️ **Cheaper prices found** 🤑\n\n") || !strings.Contains(message, "Best price: Boots\n") {
+		t.Errorf("expected the default message and product templates, got %q", message)
+	}
+}
+
+func TestTemplatesErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		templates TemplatesTOML
+		expected  string
+	}{
+		{name: "syntax", templates: TemplatesTOML{Product: "{{.Product"}, expected: "error parsing product template"},
+		{name: "unknown field", templates: TemplatesTOML{Price: "{{.Shop}}"}, expected: "error rendering deals template"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewTemplates(test.templates)
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("expected an error containing %q, got %v", test.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type unit struct {
+	base   string
+	factor float64
+}
+
+var units = map[string]unit{
+	"ml":    {base: "ml", factor: 1},
+	"l":     {base: "ml", factor: 1000},
+	"g":     {base: "g", factor: 1},
+	"kg":    {base: "g", factor: 1000},
+	"count": {base: "count", factor: 1},
+}
+
+// normaliseSize converts a size to its base unit (ml, g or count), so sizes given in different units can be compared
+func normaliseSize(size float64, unitName string) (float64, string, error) {
+	if size == 0 && unitName == "" {
+		return 0, "", nil
+	}
+	if size <= 0 {
+		return 0, "", fmt.Errorf("size must be positive, got %v", size)
+	}
+
+	u, ok := units[strings.ToLower(unitName)]
+	if !ok {
+		return 0, "", fmt.Errorf("unknown unit %q, expected one of ml, l, g, kg or count", unitName)
+	}
+
+	return size * u.factor, u.base, nil
+}
+
+// formatSize writes a size in its base unit for display, e.g. 50ml or 3 items
+func formatSize(size float64, base string) string {
+	if base == "count" {
+		if size == 1 {
+			return "1 item"
+		}
+		return fmt.Sprintf("%g items", size)
+	}
+	return fmt.Sprintf("%g%s", size, base)
+}
+
+// unitPriceQuantity is the quantity unit prices are shown for, e.g. £/100ml
+func unitPriceQuantity(base string) (float64, string) {
+	if base == "count" {
+		return 1, "item"
+	}
+	return 100, "100" + base
+}
+
+// comparablePrice scales a price for a listing of the given size to the product's own pack size, so listings of
+// different sizes can be compared against each other and the base price
+func comparablePrice(product *Product, size, price float64) float64 {
+	if product.Size <= 0 || size <= 0 {
+		return price
+	}
+	return price / size * product.Size
+}
+
+func (s SuccessScrape) comparablePrice(product *Product, price float64) float64 {
+	return comparablePrice(product, s.Size, price)
+}
+
+func (s SuccessScrape) ComparablePrice(product *Product) float64 {
+	return s.comparablePrice(product, s.Price)
+}
+
+func (s SuccessScrape) ComparableCachedPrice(product *Product) *float64 {
+	if s.CachedPrice == nil {
+		return nil
+	}
+
+	cachedPrice := s.comparablePrice(product, *s.CachedPrice)
+	return &cachedPrice
+}
+
+// EffectivePrice is the cost of buying the listing on its own, including delivery or collection
+func (s SuccessScrape) EffectivePrice() float64 {
+	cost, _ := s.Retailer.FulfilmentCost(s.Price)
+	return s.Price + cost
+}
+
+func (s SuccessScrape) ComparableEffectivePrice(product *Product) float64 {
+	return s.comparablePrice(product, s.EffectivePrice())
+}
+
+func (s SuccessScrape) ComparableCachedEffectivePrice(product *Product) *float64 {
+	if s.CachedPrice == nil {
+		return nil
+	}
+
+	cost, _ := s.Retailer.FulfilmentCost(*s.CachedPrice)
+	cachedPrice := s.comparablePrice(product, *s.CachedPrice+cost)
+	return &cachedPrice
+}
+
+func (s SuccessScrape) formatUnitPrice(price float64) string {
+	if s.Size <= 0 {
+		return ""
+	}
+
+	quantity, label := unitPriceQuantity(s.Unit)
+	return fmt.Sprintf(" (£%.2f/%s)", price/s.Size*quantity, label)
+}
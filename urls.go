@@ -0,0 +1,207 @@
+package main
+
+import (
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var amazonAsinPattern = regexp.MustCompile(`(?:/dp/|/gp/product/|/gp/aw/d/|-dp-)([A-Z0-9]{10})(?:[/?]|$)`)
+
+var trackingParams = map[string]bool{
+	"gclid":      true,
+	"fbclid":     true,
+	"msclkid":    true,
+	"gbraid":     true,
+	"wbraid":     true,
+	"gad_source": true,
+	"srsltid":    true,
+	"ref":        true,
+	"ref_":       true,
+	"tag":        true,
+	"psc":        true,
+	"th":         true,
+	"cmp":        true,
+	"mc_cid":     true,
+	"mc_eid":     true,
+	"_ga":        true,
+}
+
+// CanonicalUrl normalises a product link, stripping tracking parameters and reducing Amazon links to their ASIN
+func CanonicalUrl(link string) string {
+	parsed, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || parsed.Host == "" {
+		return link
+	}
+
+	parsed.Scheme = "https"
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Fragment = ""
+
+	if domain, ok := amazonDomain(parsed.Host); ok {
+		if matches := amazonAsinPattern.FindStringSubmatch(parsed.Path); matches != nil {
+			return "https://www." + domain + "/dp/" + matches[1]
+		}
+	}
+
+	query := parsed.Query()
+	for param := range query {
+		if trackingParams[strings.ToLower(param)] || strings.HasPrefix(strings.ToLower(param), "utm_") {
+			query.Del(param)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String()
+}
+
+// amazonDomain returns the Amazon domain for a host such as smile.amazon.co.uk
+func amazonDomain(host string) (string, bool) {
+	parts := strings.Split(host, ".")
+	for i, part := range parts {
+		if part == "amazon" {
+			return strings.Join(parts[i:], "."), true
+		}
+	}
+	return "", false
+}
+
+// listingIdentity is used to compare listings, ignoring differences that don't change the page
+func listingIdentity(link string) string {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+
+	return strings.TrimPrefix(parsed.Host, "www.") + strings.TrimSuffix(strings.ToLower(parsed.Path), "/") + "?" + parsed.RawQuery
+}
+
+type DuplicateListing struct {
+	Url      string
+	Retailer *Retailer
+	Products []*Product
+}
+
+func (d DuplicateListing) LogValue() slog.Value {
+	names := make([]string, len(d.Products))
+	for i, product := range d.Products {
+		names[i] = product.Name
+	}
+
+	return slog.GroupValue(
+		slog.String("url", d.Url),
+		slog.String("retailer", d.Retailer.Name),
+		slog.Any("products", names),
+	)
+}
+
+// FindDuplicateListings returns the listings configured under more than one product
+func (p Products) FindDuplicateListings() []DuplicateListing {
+	var order []string
+	duplicates := make(map[string]*DuplicateListing)
+
+	for i, product := range p {
+		for _, link := range product.Links {
+			identity := listingIdentity(link.Url)
+
+			duplicate, ok := duplicates[identity]
+			if !ok {
+				order = append(order, identity)
+				duplicates[identity] = &DuplicateListing{Url: link.Url, Retailer: link.Retailer, Products: []*Product{&p[i]}}
+				continue
+			}
+
+			if duplicate.Products[len(duplicate.Products)-1] != &p[i] {
+				duplicate.Products = append(duplicate.Products, &p[i])
+			}
+		}
+	}
+
+	var found []DuplicateListing
+	for _, identity := range order {
+		if duplicate := duplicates[identity]; len(duplicate.Products) > 1 {
+			found = append(found, *duplicate)
+		}
+	}
+
+	return found
+}
+
+// ApplyCanonicalUrls replaces links with the canonical URL recorded from their page on a previous scrape
+func (p Products) ApplyCanonicalUrls(cache *Cache) error {
+	canonicalUrls, err := cache.GetCanonicalUrls()
+	if err != nil {
+		return err
+	}
+
+	for _, product := range p {
+		for _, link := range product.Links {
+			key := CacheKey{Retailer: link.Retailer.Name, Product: product.Name, Label: link.Label}
+			if canonical, ok := canonicalUrls[key]; ok && canonical.Url == link.Url {
+				link.Url = canonical.CanonicalUrl
+			}
+		}
+	}
+
+	return nil
+}
+
+// RecordCanonicalUrls stores the canonical URL given by each listing's page the first time it is scraped, and
+// uses it for later scrapes. Listings with a health issue are skipped, as their page may no longer be the product.
+func (p Products) RecordCanonicalUrls(cache *Cache, prices map[*Product][]SuccessScrape, healthIssues []ListingHealth) error {
+	unhealthy := make(map[CacheKey]bool)
+	for _, health := range healthIssues {
+		unhealthy[health.Key()] = true
+	}
+
+	known, err := cache.GetCanonicalUrls()
+	if err != nil {
+		return err
+	}
+
+	updates := make(map[CacheKey]CanonicalListing)
+	for product, scrapes := range prices {
+		for _, scrape := range scrapes {
+			key := scrape.Key(product)
+			if listing, ok := known[key]; ok && (listing.Url == scrape.Url || listing.CanonicalUrl == scrape.Url) {
+				continue
+			}
+			if unhealthy[key] || scrape.CanonicalUrl == "" {
+				continue
+			}
+
+			canonical := CanonicalUrl(scrape.CanonicalUrl)
+			if !samePageHost(scrape.Url, canonical) {
+				continue
+			}
+
+			updates[key] = CanonicalListing{Url: scrape.Url, CanonicalUrl: canonical}
+			for _, link := range product.Links {
+				if link.Retailer == scrape.Retailer && link.Label == scrape.Label {
+					link.Url = canonical
+				}
+			}
+		}
+	}
+
+	return cache.SetCanonicalUrls(updates)
+}
+
+func samePageHost(a, b string) bool {
+	aUrl, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	bUrl, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+
+	return strings.TrimPrefix(aUrl.Host, "www.") == strings.TrimPrefix(bUrl.Host, "www.")
+}
+
+type CanonicalListing struct {
+	Url          string
+	CanonicalUrl string
+}
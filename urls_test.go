@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCanonicalUrl(t *testing.T) {
+	tests := map[string]string{
+		// Amazon vanity paths, tracking parameters and hosts reduce to the ASIN
+		"https://www.amazon.co.uk/INKEY-List-Antioxidant-Serum-Protect-dp-B09N9ZKWT8/dp/B09N9ZKWT8": "https://www.amazon.co.uk/dp/B09N9ZKWT8",
+		"https://amazon.co.uk/BYOMA-Moisturizing-Gel-Cream-50ml/dp/B0BJ74DJXG?tag=abc-21&psc=1":     "https://www.amazon.co.uk/dp/B0BJ74DJXG",
+		"http://smile.amazon.co.uk/gp/product/B09MRD1648/ref=ppx_yo_dt_b_asin_title":                "https://www.amazon.co.uk/dp/B09MRD1648",
+		// Other retailers keep their path and non-tracking parameters
+		"https://www.boots.com/byoma-moisturizing-gel-cream-50ml-10307026?utm_source=email&colour=red#reviews": "https://www.boots.com/byoma-moisturizing-gel-cream-50ml-10307026?colour=red",
+		"https://WWW.Superdrug.com/p/123456?gclid=abc":                                                         "https://www.superdrug.com/p/123456",
+	}
+
+	for link, expected := range tests {
+		if actual := CanonicalUrl(link); actual != expected {
+			t.Errorf("unexpected canonical URL for %s: expected %s, got %s", link, expected, actual)
+		}
+	}
+}
+
+func TestFindDuplicateListings(t *testing.T) {
+	retailer := &Retailer{Name: "Test Retailer"}
+	products := Products{
+		{Name: "Test Product", Links: []*Link{{Retailer: retailer, Url: "https://www.test.com/1"}}},
+		{Name: "Test Product 2", Links: []*Link{{Retailer: retailer, Url: "https://test.com/1/"}, {Retailer: retailer, Url: "https://test.com/2", Label: "Multipack"}}},
+		{Name: "Test Product 3", Links: []*Link{{Retailer: retailer, Url: "https://test.com/3"}}},
+	}
+
+	duplicates := products.FindDuplicateListings()
+	if len(duplicates) != 1 {
+		t.Fatalf("unexpected length: expected 1, got %d", len(duplicates))
+	}
+	if len(duplicates[0].Products) != 2 || duplicates[0].Products[1].Name != "Test Product 2" {
+		t.Errorf("unexpected duplicate products: %+v", duplicates[0].Products)
+	}
+}
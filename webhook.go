@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const webhookSignatureHeader = "X-Signature-256"
+
+// WebhookPayload is the JSON body posted to webhooks
+type WebhookPayload struct {
+	Kind   NotificationKind `json:"kind"`
+	Title  string           `json:"title"`
+	SentAt time.Time        `json:"sent_at"`
+	// Every deal price, cheapest first for each product
+	Deals    []WebhookDeal `json:"deals,omitempty"`
+	Sections []Section     `json:"sections,omitempty"`
+	Summary  string        `json:"summary,omitempty"`
+}
+
+type WebhookDeal struct {
+	Product     string      `json:"product"`
+	Category    string      `json:"category"`
+	Retailer    string      `json:"retailer"`
+	Label       string      `json:"label,omitempty"`
+	Url         string      `json:"url"`
+	Price       float64     `json:"price"`
+	BasePrice   float64     `json:"base_price"`
+	CachedPrice *float64    `json:"cached_price"`
+	Discount    float64     `json:"discount"`
+	DiscountPct float64     `json:"discount_pct"`
+	Change      PriceChange `json:"change"`
+}
+
+func newWebhookPayload(notification Notification, sentAt time.Time) WebhookPayload {
+	payload := WebhookPayload{
+		Kind:     notification.Kind,
+		Title:    notification.Title,
+		SentAt:   sentAt,
+		Sections: notification.Sections,
+		Summary:  notification.Summary,
+	}
+
+	for _, category := range notification.Categories {
+		for _, deal := range category.Deals {
+			for _, price := range deal.Prices {
+				payload.Deals = append(payload.Deals, WebhookDeal{
+					Product:     deal.Product,
+					Category:    deal.Category,
+					Retailer:    price.Retailer,
+					Label:       price.Label,
+					Url:         price.Url,
+					Price:       price.Price,
+					BasePrice:   deal.BasePrice,
+					CachedPrice: price.CachedPrice,
+					Discount:    price.Discount,
+					DiscountPct: price.DiscountPct,
+					Change:      price.Change,
+				})
+			}
+		}
+	}
+
+	return payload
+}
+
+// WebhookClient posts notifications as JSON, signing the body with an HMAC-SHA256 of the secret if one is given
+type WebhookClient struct {
+	client  *http.Client
+	url     string
+	headers map[string]string
+	secret  string
+}
+
+func NewWebhookClient(config Webhook) (*WebhookClient, error) {
+	if config.Url == "" {
+		return nil, fmt.Errorf("webhook url is required")
+	}
+
+	return &WebhookClient{
+		client:  &http.Client{Timeout: 30 * time.Second},
+		url:     config.Url,
+		headers: config.Headers,
+		secret:  config.Secret,
+	}, nil
+}
+
+func (w *WebhookClient) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(newWebhookPayload(notification, time.Now().UTC()))
+	if err != nil {
+		return fmt.Errorf("error encoding payload: %v", err)
+	}
+
+	headers := map[string]string{}
+	for name, value := range w.headers {
+		headers[name] = value
+	}
+	if w.secret != "" {
+		headers[webhookSignatureHeader] = "sha256=" + signWebhook(w.secret, body)
+	}
+
+	return postJSON(ctx, w.client, w.url, body, headers)
+}
+
+func (w *WebhookClient) Stop() error {
+	return nil
+}
+
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// maxRateLimitRetries is how many times a request is retried after being rate limited
+const maxRateLimitRetries = 3
+
+// postJSON posts a JSON body, waiting and retrying as asked when rate limited
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte, headers map[string]string) error {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < maxRateLimitRetries {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retryAfter(resp.Header.Get("Retry-After"))):
+			}
+			continue
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("request returned status %d", resp.StatusCode)
+		}
+
+		return nil
+	}
+}
+
+// retryAfter reads the number of seconds to wait from a Retry-After header, which may be fractional
+func retryAfter(header string) time.Duration {
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds < 0 {
+		return time.Second
+	}
+	return min(time.Duration(seconds*float64(time.Second)), time.Minute)
+}
+
+// truncateText shortens text to at most limit characters, ending it with an ellipsis if anything was cut
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookClient(t *testing.T) {
+	var received WebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if signature := r.Header.Get(webhookSignatureHeader); signature != "sha256="+signWebhook("secret", body) {
+			t.Errorf("unexpected signature: %s", signature)
+		}
+		if token := r.Header.Get("Authorization"); token != "Bearer token" {
+			t.Errorf("unexpected authorization header: %s", token)
+		}
+
+		err = json.Unmarshal(body, &received)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewWebhookClient(Webhook{Url: server.URL, Headers: map[string]string{"Authorization": "Bearer token"}, Secret: "secret"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	retailer := &Retailer{Name: "Test Retailer"}
+	prices := map[*Product][]SuccessScrape{
+		&Product{Name: "Serum", BasePrice: 10.00, Category: "skincare"}: {
+			{Retailer: retailer, Price: 7.00, Url: "https://test.com/1", CachedPrice: floatPtr(8.00)},
+		},
+	}
+
+	err = notify(context.Background(), prices, client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if received.Kind != NotificationDeals || len(received.Deals) != 1 {
+		t.Fatalf("unexpected payload: %+v", received)
+	}
+	deal := received.Deals[0]
+	if deal.Product != "Serum" || deal.Category != "skincare" || deal.Retailer != "Test Retailer" || deal.Url != "https://test.com/1" {
+		t.Errorf("unexpected deal: %+v", deal)
+	}
+	if deal.Price != 7.00 || deal.BasePrice != 10.00 || deal.CachedPrice == nil || *deal.CachedPrice != 8.00 || deal.Discount != 3.00 {
+		t.Errorf("unexpected deal prices: %+v", deal)
+	}
+}
+
+func TestWebhookClientError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client, err := NewWebhookClient(Webhook{Url: server.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = client.Notify(context.Background(), Notification{Kind: NotificationOutages})
+	if err == nil {
+		t.Error("expected an error for a failed request")
+	}
+}
+
+func TestWebhookClientRateLimited(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.Header().Set("Retry-After", "0.01")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewWebhookClient(Webhook{Url: server.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = client.Notify(context.Background(), Notification{Kind: NotificationOutages})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("expected 3 requests, got %d", requests)
+	}
+}